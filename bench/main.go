@@ -6,6 +6,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"runtime"
 	"time"
 
@@ -277,6 +278,12 @@ func runBinaryReuse(b *bench.B) {
 		reader.Reset(enc)
 		_ = decoder.Decode(&out)
 	})
+
+	buffered := binary.NewBufferedEncoder(io.Discard, 4096)
+	b.Run("binary/buffered-enc", func(int) {
+		_ = buffered.Encode(&v)
+		_ = buffered.Flush()
+	})
 }
 
 type tracePayload struct {
@@ -373,6 +380,19 @@ func runBinaryUnion(b *bench.B) {
 		reader.Reset(enc)
 		_ = decoder.Decode(&out)
 	})
+
+	large := unionPayload{
+		Image: &unionImage{
+			Width:  4096,
+			Height: 4096,
+			Bytes:  makeBytes(1 << 20),
+		},
+	}
+	largeEnc, _ := binary.Marshal(&large)
+
+	b.Run("union/large-enc", func(int) { binary.Marshal(&large) })
+	b.Run("union/large-dec", func(int) { binary.Unmarshal(largeEnc, &out) })
+	b.Run("union/large-stream-enc", func(int) { binary.MarshalTo(&large, io.Discard) })
 }
 
 // ------------------------------------------------------------------------------
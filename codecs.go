@@ -240,7 +240,7 @@ func (c *byteSliceCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			if readErr != nil {
 				return readErr
 			}
-			rv.SetBytes(data[:n:n])
+			rv.SetBytes(d.own(data))
 			return nil
 		}
 		if err = d.ensureAvailable(n); err != nil {
@@ -372,6 +372,9 @@ func (c *boolSliceCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			if readErr != nil {
 				return readErr
 			}
+			if err = d.checkBools(data); err != nil {
+				return
+			}
 			if err = resizeSliceChecked(rv, n); err != nil {
 				return
 			}
@@ -385,7 +388,10 @@ func (c *boolSliceCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			return
 		}
 		if l > 0 {
-			_, err = d.Read(unsafe.Slice((*byte)(rv.UnsafePointer()), n))
+			data := unsafe.Slice((*byte)(rv.UnsafePointer()), n)
+			if _, err = d.Read(data); err == nil {
+				err = d.checkBools(data)
+			}
 		}
 	}
 	return
@@ -811,6 +817,8 @@ func (c reflectStructCodec) hasWireData() bool {
 
 func isZeroWireCodec(codec Codec) bool {
 	switch codec := codec.(type) {
+	case *rawCodec:
+		return isZeroWireCodec(codec.codec)
 	case *reflectStructCodec:
 		return !codec.hasWireData()
 	case *reflectCollectionCodec:
@@ -826,10 +834,14 @@ func isZeroWireCodec(codec Codec) bool {
 
 func wireMinBytes(codec Codec) int {
 	switch codec := codec.(type) {
-	case *primitiveCodec, *reflectPointerCodec, *byteSliceCodec, *boolSliceCodec, *varSliceCodec, *reflectMapCodec, *customCodec, *uint64MapCodec:
+	case *primitiveCodec, *reflectPointerCodec, *lazyCodec, *byteSliceCodec, *boolSliceCodec, *varSliceCodec, *reflectMapCodec, *customCodec, *uint64MapCodec:
 		return 1
-	case *reflectUnionCodec:
+	case *reflectUnionCodec, *oneOfCodec, *versionedCodec, *interfaceCodec:
 		return 2
+	case *reflectMixedCodec:
+		return wireMinBytes(codec.fields) + 2
+	case *rawCodec:
+		return wireMinBytes(codec.codec)
 	case stringMapCodec[string], stringMapCodec[[]byte], stringMapCodec[uint64]:
 		return 1
 	case *reflectStructCodec:
@@ -1169,6 +1181,7 @@ func (c *customCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			if buffer, err = d.Slice(n); err != nil {
 				return err
 			}
+			buffer = d.own(buffer)
 		} else {
 			if err = d.ensureAvailable(n); err != nil {
 				return err
@@ -1282,6 +1295,9 @@ func readStringMapValue[V stringMapValue](d *Decoder, arena *[]byte) (V, error)
 	switch any(zero).(type) {
 	case string:
 		b, err := d.ReadSlice()
+		if err == nil {
+			err = d.checkUTF8(b)
+		}
 		if err != nil {
 			return zero, err
 		}
@@ -1299,6 +1315,8 @@ func readStringMapValue[V stringMapValue](d *Decoder, arena *[]byte) (V, error)
 		}
 		if arena != nil {
 			b = arenaBytes(arena, b)
+		} else {
+			b = d.own(b)
 		}
 		return any(b).(V), nil
 	case uint64:
@@ -1383,6 +1401,9 @@ func (stringMapCodec[V]) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 		if err = readErr; err != nil {
 			return
 		}
+		if err = d.checkUTF8(keyBytes); err != nil {
+			return
+		}
 		var key string
 		if arena != nil {
 			key = arenaString(arena, keyBytes)
@@ -1394,6 +1415,9 @@ func (stringMapCodec[V]) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			return
 		}
 		m[key] = value
+		if err = d.checkUnique(len(m), i+1); err != nil {
+			return
+		}
 	}
 	return
 }
@@ -1455,6 +1479,9 @@ func (uint64MapCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 			return
 		}
 		m[key] = value
+		if err = d.checkUnique(len(m), i+1); err != nil {
+			return
+		}
 	}
 	return
 }
@@ -1536,6 +1563,9 @@ func (c *reflectMapCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 				return
 			}
 			rv.SetMapIndex(kv, vv)
+			if err = d.checkUnique(rv.Len(), i+1); err != nil {
+				return
+			}
 		}
 	}
 	return
@@ -1613,6 +1643,9 @@ func (c *reflectMapCodec) readKey(d *Decoder, key reflect.Value, arena *[]byte)
 			return err
 		}
 		b, err := d.Slice(int(l))
+		if err == nil {
+			err = d.checkUTF8(b)
+		}
 		if err != nil {
 			return err
 		}
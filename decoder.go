@@ -11,6 +11,7 @@ import (
 	"math"
 	"reflect"
 	"sync"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -27,22 +28,109 @@ func Unmarshal(b []byte, v any) (err error) {
 	return
 }
 
+// UnmarshalPrefix decodes the value at the start of b into v and returns the number of
+// bytes it was encoded with, so that concatenated values can be decoded one by one.
+func UnmarshalPrefix(b []byte, v any) (n int, err error) {
+	d := decoders.Get().(*Decoder)
+	d.reader.(*sliceReader).Reset(b)
+	if err = d.Decode(v); err == nil {
+		n = d.slice.offset
+	}
+	d.arena = nil
+	decoders.Put(d)
+	return
+}
+
+// Validate checks that b holds a well formed encoding of a value of the type of v, which
+// may be a pointer to it, without decoding it. It accepts the inputs Unmarshal accepts.
+func Validate(b []byte, v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return errors.New("binary: cannot validate against nil value")
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	d := decoders.Get().(*Decoder)
+	d.reader.(*sliceReader).Reset(b)
+	err := d.Skip(t)
+	d.arena = nil
+	decoders.Put(d)
+	return err
+}
+
+// ErrNonCanonical is returned by a strict decoder when the input is not the canonical
+// encoding of the value, such as a varint with redundant bytes, a bool byte other than 0
+// or 1, or a duplicate map key.
+var ErrNonCanonical = errors.New("binary: non-canonical encoding")
+
+// ErrInvalidUTF8 is returned by a decoder that validates strings when one is not valid UTF-8.
+var ErrInvalidUTF8 = errors.New("binary: invalid UTF-8 in string")
+
+// ErrTrailingData is returned by a decoder that disallows trailing data when bytes remain
+// after the decoded value.
+var ErrTrailingData = errors.New("binary: trailing data after value")
+
 type Decoder struct {
 	reader  reader
 	slice   *sliceReader
+	source  *bytes.Buffer // buffer advanced by the bytes consumed
+	peeker  peeker        // buffered reader decoded in place when it holds a whole value
+	offset  int64         // bytes consumed by the source or peeker, not counted by the reader
+	flags   decodeFlags
 	arena   []byte
 	scratch [10]byte
 	last    reflect.Type
 	codec   Codec
 }
 
+// decodeFlags are the options of a decoder, set by its methods.
+type decodeFlags uint8
+
+const (
+	flagNoTrailing decodeFlags = 1 << iota // input must end after each value
+	flagStrict                             // input must be canonical
+	flagUTF8                               // strings must be valid UTF-8
+	flagCopy                               // slices read from streams are copied
+)
+
+// peeker is a buffered reader, such as bufio.Reader, whose buffer can be decoded from
+// directly and then discarded.
+type peeker interface {
+	Peek(n int) ([]byte, error)
+	Discard(n int) (int, error)
+	Buffered() int
+}
+
 func NewDecoder(r io.Reader) *Decoder {
-	reader := newReader(r)
-	d := &Decoder{reader: reader}
-	d.slice, _ = reader.(*sliceReader)
+	d := new(Decoder)
+	d.Reset(r)
 	return d
 }
 
+// Reset makes the decoder read from r, keeping its options and reusing its stream buffer,
+// so that decoders can be pooled. Slices read from the previous input become invalid.
+func (d *Decoder) Reset(r io.Reader) {
+	if stream, ok := d.reader.(*streamReader); ok && isStream(r) {
+		stream.Reset(r)
+	} else {
+		d.reader = newReader(r)
+	}
+	d.slice, _ = d.reader.(*sliceReader)
+	d.source, d.peeker = nil, nil
+	if r != nil && !isNilInterface(r) {
+		switch v := r.(type) {
+		case *bytes.Buffer:
+			d.source = v
+		case peeker:
+			d.peeker = v
+		}
+	}
+	d.offset = 0
+	d.arena = nil
+	d.setFlags(d.flags)
+}
+
 func (d *Decoder) Decode(v any) (err error) {
 	d.arena = nil
 	rv := reflect.Indirect(reflect.ValueOf(v))
@@ -58,10 +146,195 @@ func (d *Decoder) Decode(v any) (err error) {
 		d.last = t
 		d.codec = c
 	}
-	err = c.DecodeTo(d, rv)
+	return d.decodeValue(c, rv, t)
+}
+
+// DisallowTrailingData causes Decode and Skip to return ErrTrailingData when the input
+// holds more bytes after the value. On streams, this reads ahead to the end of the input,
+// so it is only meant for streams holding a single value.
+func (d *Decoder) DisallowTrailingData() {
+	d.flags |= flagNoTrailing
+}
+
+// Strict causes Decode to return ErrNonCanonical unless the input is the canonical
+// encoding of the value, so that equal values always have equal encodings. It rejects
+// varints with redundant bytes, bool bytes other than 0 and 1, and duplicate map keys.
+func (d *Decoder) Strict() {
+	d.setFlags(d.flags | flagStrict)
+}
+
+// ValidateUTF8 causes Decode to return ErrInvalidUTF8 when a string is not valid UTF-8.
+func (d *Decoder) ValidateUTF8() {
+	d.setFlags(d.flags | flagUTF8)
+}
+
+// CopySlices causes the slices read from a stream, including the ones held by nocopy
+// types, to be copies that remain valid after the next Decode. By default, they point
+// into the buffer of the decoder, which is reused by the next Decode. A bufio.Reader is
+// then read through the buffer of the decoder, rather than decoded in place from its own.
+func (d *Decoder) CopySlices() {
+	d.setFlags(d.flags | flagCopy)
+}
+
+func (d *Decoder) setFlags(flags decodeFlags) {
+	d.flags = flags
+	strict := flags&flagStrict != 0
+	switch r := d.reader.(type) {
+	case *sliceReader:
+		r.strict = strict
+	case *streamReader:
+		r.strict = strict
+		r.copy = flags&flagCopy != 0
+	}
+}
+
+// own returns b, which was read from the decoder, as a slice the caller can keep.
+func (d *Decoder) own(b []byte) []byte {
+	if r, ok := d.reader.(*streamReader); ok && !r.copy && b != nil {
+		return append(make([]byte, 0, len(b)), b...)
+	}
+	return b
+}
+
+// borrow returns a pooled decoder reading from b with the same checks as d.
+func (d *Decoder) borrow(b []byte) *Decoder {
+	return borrowDecoder(b, d.flags&^flagNoTrailing)
+}
+
+// borrowDecoder returns a pooled decoder reading from b with the given flags, which is
+// given back with release.
+func borrowDecoder(b []byte, flags decodeFlags) *Decoder {
+	dec := decoders.Get().(*Decoder)
+	dec.slice.Reset(b)
+	dec.setFlags(flags)
+	dec.arena = nil
+	return dec
+}
+
+func release(dec *Decoder) {
+	dec.arena = nil
+	dec.setFlags(0)
+	decoders.Put(dec)
+}
+
+// InputOffset returns the number of bytes consumed from the input so far.
+func (d *Decoder) InputOffset() int64 {
+	switch r := d.reader.(type) {
+	case *sliceReader:
+		return d.offset + int64(r.offset)
+	case *streamReader:
+		return d.offset + r.consumed()
+	default:
+		return d.offset
+	}
+}
+
+// Skip steps over a value of type t, checking that it is well formed without decoding it.
+func (d *Decoder) Skip(t reflect.Type) error {
+	c, err := scan(t)
+	if err != nil {
+		return err
+	}
+	return d.decodeValue(c, reflect.Value{}, t)
+}
+
+// decodeValue decodes into rv with the codec, or skips a value of type t if rv is invalid.
+func (d *Decoder) decodeValue(c Codec, rv reflect.Value, t reflect.Type) (err error) {
+	if r, ok := d.reader.(*streamReader); ok {
+		r.release()
+	}
+	switch {
+	case d.source != nil:
+		err = d.decodeSource(c, rv, t)
+	case d.peeker != nil && d.flags&flagCopy == 0: // copies come from the stream reader
+		err = d.decodePeek(c, rv, t)
+	default:
+		err = d.decodeTo(c, rv, t)
+	}
+	if err == nil && d.flags&flagNoTrailing != 0 {
+		err = d.checkTrailing()
+	}
 	return
 }
 
+// checkTrailing returns ErrTrailingData unless the input ends after the decoded value.
+func (d *Decoder) checkTrailing() error {
+	switch {
+	case d.source != nil && d.source.Len() > 0:
+		return ErrTrailingData
+	case d.source != nil:
+		return nil
+	case d.slice != nil && d.slice.Len() > 0:
+		return ErrTrailingData
+	case d.slice != nil:
+		return nil
+	}
+
+	switch _, err := d.reader.ReadByte(); err {
+	case io.EOF:
+		return nil
+	case nil:
+		return ErrTrailingData
+	default:
+		return err
+	}
+}
+
+func (d *Decoder) decodeTo(c Codec, rv reflect.Value, t reflect.Type) error {
+	if !rv.IsValid() {
+		return skipValue(d, c, t)
+	}
+	return c.DecodeTo(d, rv)
+}
+
+// decodeSource decodes from the unread bytes of the buffer, and advances it by the bytes
+// consumed once the value is decoded. Reads made directly on the decoder in between see
+// the bytes left unread by the previous call, and are consumed by the next one.
+func (d *Decoder) decodeSource(c Codec, rv reflect.Value, t reflect.Type) error {
+	d.advanceSource()
+	if err := d.decodeTo(c, rv, t); err != nil {
+		d.slice.offset = 0
+		return err
+	}
+	d.advanceSource()
+	return nil
+}
+
+func (d *Decoder) advanceSource() {
+	d.offset += int64(d.slice.offset)
+	d.source.Next(d.slice.offset)
+	d.slice.Reset(d.source.Bytes())
+}
+
+// decodePeek decodes the value in place from the buffer of the reader and discards the
+// bytes consumed, falling back to reading the stream when the value is not fully buffered.
+// The value is reset before then, so that nothing is left from the partial attempt.
+func (d *Decoder) decodePeek(c Codec, rv reflect.Value, t reflect.Type) error {
+	if d.peeker.Buffered() == 0 {
+		d.peeker.Peek(1) // fill the buffer
+	}
+	if b, _ := d.peeker.Peek(d.peeker.Buffered()); len(b) > 0 {
+		slice := sliceReader{buffer: b, strict: d.flags&flagStrict != 0}
+		reader := d.reader
+		d.reader, d.slice = &slice, &slice
+		err := d.decodeTo(c, rv, t)
+		d.reader, d.slice = reader, nil
+		switch err {
+		case nil:
+			n, err := d.peeker.Discard(slice.offset)
+			d.offset += int64(n)
+			return err
+		case io.EOF, io.ErrUnexpectedEOF:
+			if rv.IsValid() {
+				rv.SetZero()
+			}
+		default:
+			return err
+		}
+	}
+	return d.decodeTo(c, rv, t)
+}
+
 func (d *Decoder) Read(b []byte) (int, error) {
 	return d.reader.Read(b)
 }
@@ -116,24 +389,56 @@ func (d *Decoder) ReadFloat64() (out float64, err error) {
 
 func (d *Decoder) ReadBool() (bool, error) {
 	b, err := d.reader.ReadByte()
+	if b > 1 && d.flags&flagStrict != 0 {
+		return false, ErrNonCanonical
+	}
 	return b == 1, err
 }
 
 func (d *Decoder) ReadString() (out string, err error) {
 	var b []byte
 	if b, err = d.ReadSlice(); err == nil {
-		out = d.stringFromBytes(b)
+		if err = d.checkUTF8(b); err == nil {
+			out = d.stringFromBytes(b)
+		}
 	}
 	return
 }
 
-func (d *Decoder) stringFromBytes(b []byte) string {
-	if d.slice == nil {
-		if _, ok := d.reader.(*streamReader); ok {
-			// streamReader allocates and owns this buffer, so it cannot be mutated after return.
-			return unsafe.String(unsafe.SliceData(b), len(b))
+// checkUTF8 returns ErrInvalidUTF8 if strings are validated and b is not valid UTF-8.
+func (d *Decoder) checkUTF8(b []byte) error {
+	if d.flags&flagUTF8 != 0 && !utf8.Valid(b) {
+		return ErrInvalidUTF8
+	}
+	return nil
+}
+
+// checkBools returns ErrNonCanonical if the decoder is strict and b holds a byte other than 0 or 1.
+func (d *Decoder) checkBools(b []byte) error {
+	if d.flags&flagStrict != 0 {
+		for _, v := range b {
+			if v > 1 {
+				return ErrNonCanonical
+			}
 		}
 	}
+	return nil
+}
+
+// checkUnique returns ErrNonCanonical if the decoder is strict and a map holding size
+// entries after inserting count of them was given a duplicate key.
+func (d *Decoder) checkUnique(size, count int) error {
+	if size != count && d.flags&flagStrict != 0 {
+		return ErrNonCanonical
+	}
+	return nil
+}
+
+func (d *Decoder) stringFromBytes(b []byte) string {
+	if r, ok := d.reader.(*streamReader); ok && r.copy {
+		// streamReader allocates a copy it does not keep, so it cannot be mutated after return.
+		return unsafe.String(unsafe.SliceData(b), len(b))
+	}
 	return string(b)
 }
 
@@ -142,6 +447,9 @@ func (d *Decoder) readString(old string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if err = d.checkUTF8(b); err != nil {
+		return "", err
+	}
 	if len(old) == len(b) && bytes.Equal(ToBytes(old), b) {
 		return old, nil
 	}
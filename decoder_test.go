@@ -4,8 +4,12 @@
 package binary
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"io"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -72,3 +76,272 @@ func TestStreamStringLifetime(t *testing.T) {
 	assert.Equal(t, "first", gotFirst)
 	assert.Equal(t, "second", gotSecond)
 }
+
+func TestDecodeBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	assert.NoError(t, e.Encode(&textPayload{Msg: "first"}))
+
+	// Values written after the decoder is created are seen as well
+	d := NewDecoder(&buf)
+	assert.NoError(t, e.Encode(&textPayload{Msg: "second"}))
+	for _, expect := range []string{"first", "second"} {
+		var out textPayload
+		assert.NoError(t, d.Decode(&out))
+		assert.Equal(t, expect, out.Msg)
+	}
+	assert.Equal(t, 0, buf.Len())
+
+	// A partial value is left in the buffer until it is complete
+	encoded, err := Marshal(&imagePayload{Width: 300, Height: 400})
+	assert.NoError(t, err)
+	buf.Write(encoded[:1])
+	var image imagePayload
+	assert.Error(t, d.Decode(&image))
+	assert.Equal(t, 1, buf.Len())
+	buf.Write(encoded[1:])
+	assert.NoError(t, d.Decode(&image))
+	assert.Equal(t, imagePayload{Width: 300, Height: 400}, image)
+
+	// Bytes read through the decoder are consumed on the next decode
+	buf.Write([]byte{7})
+	assert.NoError(t, e.Encode(&textPayload{Msg: "third"}))
+	d = NewDecoder(&buf)
+	v, err := d.ReadUvarint()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), v)
+
+	var text textPayload
+	assert.NoError(t, d.Decode(&text))
+	assert.Equal(t, "third", text.Msg)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestDecodeBufio(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for i := range 100 {
+		assert.NoError(t, e.Encode(&textPayload{Msg: strings.Repeat("x", i)}))
+	}
+	buf.WriteString("tail")
+
+	// Values straddling the buffer are read from the stream instead
+	r := bufio.NewReaderSize(&buf, 16)
+	d := NewDecoder(r)
+	for i := range 100 {
+		var out textPayload
+		assert.NoError(t, d.Decode(&out))
+		assert.Equal(t, strings.Repeat("x", i), out.Msg)
+	}
+
+	rest, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "tail", string(rest))
+	assert.Error(t, d.Decode(new(textPayload)))
+}
+
+var errRejected = errors.New("rejected")
+
+type rejectingValue struct{ calls *int }
+
+func (rejectingValue) MarshalBinary() ([]byte, error) { return []byte("value"), nil }
+func (v *rejectingValue) UnmarshalBinary([]byte) error {
+	*v.calls++
+	return errRejected
+}
+
+func TestDecodeBufioError(t *testing.T) {
+	b, err := Marshal(rejectingValue{})
+	assert.NoError(t, err)
+
+	// Errors of values held in the buffer are returned without reading them again
+	var calls int
+	d := NewDecoder(bufio.NewReader(bytes.NewReader(b)))
+	assert.Equal(t, errRejected, d.Decode(&rejectingValue{calls: &calls}))
+	assert.Equal(t, 1, calls)
+}
+
+// appendingValue keeps every value decoded into it.
+type appendingValue []string
+
+func (appendingValue) MarshalBinary() ([]byte, error) { return []byte("x"), nil }
+func (v *appendingValue) UnmarshalBinary(b []byte) error {
+	*v = append(*v, string(b))
+	return nil
+}
+
+func TestDecodeBufioRetry(t *testing.T) {
+	type value struct {
+		Seen appendingValue
+		Text string
+	}
+
+	b, err := Marshal(&value{Seen: appendingValue{"x"}, Text: strings.Repeat("y", 100)})
+	assert.NoError(t, err)
+
+	// The value straddles the buffer, so it is decoded again from a reset value
+	var out value
+	d := NewDecoder(bufio.NewReaderSize(bytes.NewReader(b), 16))
+	assert.NoError(t, d.Decode(&out))
+	assert.Equal(t, appendingValue{"x"}, out.Seen)
+	assert.Equal(t, strings.Repeat("y", 100), out.Text)
+}
+
+func TestUnmarshalPrefix(t *testing.T) {
+	var b []byte
+	for i := range 10 {
+		next, err := Marshal(&textPayload{Msg: strings.Repeat("x", i)})
+		assert.NoError(t, err)
+		b = append(b, next...)
+	}
+
+	for i := 0; len(b) > 0; i++ {
+		var out textPayload
+		n, err := UnmarshalPrefix(b, &out)
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, n)
+		assert.Equal(t, strings.Repeat("x", i), out.Msg)
+		b = b[n:]
+	}
+
+	n, err := UnmarshalPrefix([]byte{5, 'a'}, new(textPayload))
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestDecoderInputOffset(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for i := range 20 {
+		assert.NoError(t, e.Encode(&textPayload{Msg: strings.Repeat("x", i)}))
+	}
+	b := buf.Bytes()
+
+	for name, r := range map[string]func() io.Reader{
+		"slice":  func() io.Reader { return newSliceReader(b) },
+		"buffer": func() io.Reader { return bytes.NewBuffer(b) },
+		"bufio":  func() io.Reader { return bufio.NewReaderSize(bytes.NewReader(b), 16) },
+		"stream": func() io.Reader { return io.MultiReader(bytes.NewReader(b)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := NewDecoder(r())
+			assert.Equal(t, int64(0), d.InputOffset())
+			offset := int64(0)
+			for i := range 20 {
+				assert.NoError(t, d.Decode(new(textPayload)))
+				offset += int64(i + 1)
+				assert.Equal(t, offset, d.InputOffset())
+			}
+		})
+	}
+}
+
+func TestDecoderDisallowTrailingData(t *testing.T) {
+	b, err := Marshal(&textPayload{Msg: "hi"})
+	assert.NoError(t, err)
+	long := append(b[:len(b):len(b)], 0)
+
+	for name, r := range map[string]func([]byte) io.Reader{
+		"slice":  func(b []byte) io.Reader { return newSliceReader(b) },
+		"buffer": func(b []byte) io.Reader { return bytes.NewBuffer(b) },
+		"bufio":  func(b []byte) io.Reader { return bufio.NewReader(bytes.NewReader(b)) },
+		"stream": func(b []byte) io.Reader { return io.MultiReader(bytes.NewReader(b)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := NewDecoder(r(b))
+			d.DisallowTrailingData()
+			assert.NoError(t, d.Decode(new(textPayload)))
+
+			d = NewDecoder(r(long))
+			d.DisallowTrailingData()
+			assert.Equal(t, ErrTrailingData, d.Decode(new(textPayload)))
+
+			d = NewDecoder(r(long))
+			d.DisallowTrailingData()
+			assert.Equal(t, ErrTrailingData, d.Skip(reflect.TypeFor[textPayload]()))
+
+			// Trailing data is ignored by default
+			assert.NoError(t, NewDecoder(r(long)).Decode(new(textPayload)))
+		})
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	decode := func(b []byte, v any, strict bool) map[string]error {
+		errs := make(map[string]error)
+		for name, r := range map[string]io.Reader{
+			"slice":  newSliceReader(b),
+			"bufio":  bufio.NewReader(bytes.NewReader(b)),
+			"stream": io.MultiReader(bytes.NewReader(b)),
+		} {
+			d := NewDecoder(r)
+			if strict {
+				d.Strict()
+			}
+			errs[name] = d.Decode(reflect.New(reflect.TypeOf(v).Elem()).Interface())
+		}
+		return errs
+	}
+
+	tests := map[string]struct {
+		input []byte
+		value any
+	}{
+		"varint":       {[]byte{0x80, 0x00}, new(uint64)},
+		"varint slice": {[]byte{2, 0x02, 0x81, 0x00}, new([]int32)},
+		"length":       {[]byte{0x81, 0x00, 'a'}, new(string)},
+		"bool":         {[]byte{2}, new(bool)},
+		"bool slice":   {[]byte{3, 0, 1, 2}, new([]bool)},
+		"nil pointer":  {[]byte{3, 5}, new(*uint64)},
+		"string map":   {[]byte{2, 1, 0, 'a', 1, 'x', 1, 0, 'a', 1, 'y'}, new(map[string]string)},
+		"uint64 map":   {[]byte{2, 1, 0, 0, 0, 0, 0, 0, 0, 5, 1, 0, 0, 0, 0, 0, 0, 0, 6}, new(map[uint64]uint64)},
+		"reflect map":  {[]byte{2, 2, 0, 2, 1}, new(map[int8]int8)},
+		"union arm":    {[]byte{2, 3, 0x80, 0x00, 0x02}, new(payload)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for reader, err := range decode(tc.input, tc.value, false) {
+				assert.NoError(t, err, reader)
+			}
+			for reader, err := range decode(tc.input, tc.value, true) {
+				assert.Equal(t, ErrNonCanonical, err, reader)
+			}
+		})
+	}
+
+	// Canonical input is accepted
+	b, err := Marshal(newPathEnvelope())
+	assert.NoError(t, err)
+	for reader, err := range decode(b, new(pathEnvelope), true) {
+		assert.NoError(t, err, reader)
+	}
+}
+
+func TestDecoderValidateUTF8(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		value any
+	}{
+		"string":         {[]byte{2, 'a', 0xff}, new(string)},
+		"string slice":   {[]byte{1, 1, 0xff}, new([]string)},
+		"string map key": {[]byte{1, 1, 0, 0xff, 1, 'x'}, new(map[string]string)},
+		"string map val": {[]byte{1, 1, 0, 'a', 1, 0xff}, new(map[string]string)},
+		"reflect map":    {[]byte{1, 1, 0, 0xff, 0}, new(map[string]int)},
+		"struct":         {[]byte{1, 0xff}, new(textPayload)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, Unmarshal(tc.input, tc.value))
+
+			d := NewDecoder(bytes.NewReader(tc.input))
+			d.ValidateUTF8()
+			assert.Equal(t, ErrInvalidUTF8, d.Decode(tc.value))
+
+			d = NewDecoder(newSliceReader(tc.input))
+			d.ValidateUTF8()
+			assert.Equal(t, ErrInvalidUTF8, d.Decode(tc.value))
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"io"
 	"math"
+	"net"
 	"reflect"
 	"sync"
 )
@@ -19,6 +20,8 @@ var encoders = &sync.Pool{New: func() any {
 	return new(Encoder)
 }}
 
+const maxFramedBuffer = 64 << 10 // largest buffer kept in the pool after framing a value
+
 type marshalState struct {
 	bytes.Buffer
 	encoder Encoder
@@ -69,7 +72,22 @@ func Marshal(v any) (output []byte, err error) {
 func MarshalTo(v any, dst io.Writer) (err error) {
 	e := encoders.Get().(*Encoder)
 	e.Reset(dst)
-	err = e.Encode(v)
+	if _, ok := dst.(bufferWriter); ok || e.err != nil {
+		err = e.Encode(v)
+		encoders.Put(e)
+		return
+	}
+
+	// Gather the small writes so that the destination only sees a few large ones
+	w := writers.Get().(*bufferedWriter)
+	w.Reset(dst)
+	e.out = w
+	if err = e.Encode(v); err == nil {
+		err = w.Flush()
+	}
+	w.Reset(nil)
+	writers.Put(w)
+	e.out = nil // the writer goes back to its own pool
 	encoders.Put(e)
 	return
 }
@@ -88,8 +106,33 @@ func NewEncoder(out io.Writer) *Encoder {
 	return e
 }
 
+// NewBufferedEncoder returns an encoder gathering its writes in a buffer of the given
+// size, or of 4KB if the size is not positive, so that a writer such as a socket or a
+// file does not see a write per field. Writes of at least half the buffer bypass it.
+// Flush must be called for the buffered bytes to reach the writer.
+func NewBufferedEncoder(out io.Writer, size int) *Encoder {
+	e := &Encoder{out: newBufferedWriter(nil, size)}
+	e.Reset(out)
+	return e
+}
+
+// Flush writes any buffered bytes to the underlying writer, and returns the first error
+// encountered by the encoder. It does nothing for an encoder that is not buffered.
+func (e *Encoder) Flush() error {
+	if w, ok := e.out.(*bufferedWriter); ok && e.err == nil {
+		e.err = w.Flush()
+	}
+	return e.err
+}
+
+// Reset makes the encoder write to out. A buffered encoder stays buffered and discards
+// the bytes that were not flushed, as bufio.Writer does.
 func (e *Encoder) Reset(out io.Writer) {
-	e.out = out
+	if w, ok := e.out.(*bufferedWriter); ok {
+		w.Reset(out)
+	} else {
+		e.out = out
+	}
 	e.err = nil
 	if out == nil {
 		e.err = errNilWriter
@@ -228,3 +271,150 @@ func (e *Encoder) WriteTagged(tag uint64, body []byte) {
 		e.Write(body)
 	}
 }
+
+// ------------------------------------------------------------------------------
+
+var writers = &sync.Pool{New: func() any {
+	return newBufferedWriter(nil, 0)
+}}
+
+// bufferedWriter gathers small writes into a fixed-size buffer, and writes large ones
+// along with the buffered bytes as a single vectored write.
+type bufferedWriter struct {
+	out    io.Writer
+	buffer []byte
+	err    error
+}
+
+func newBufferedWriter(out io.Writer, size int) *bufferedWriter {
+	if size <= 0 {
+		size = 4096
+	}
+	return &bufferedWriter{out: out, buffer: make([]byte, 0, size)}
+}
+
+func (w *bufferedWriter) Reset(out io.Writer) {
+	w.out = out
+	w.buffer = w.buffer[:0]
+	w.err = nil
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	switch {
+	case w.err != nil:
+		return 0, w.err
+	case len(p) <= cap(w.buffer)-len(w.buffer):
+		w.buffer = append(w.buffer, p...)
+		return len(p), nil
+	case len(p) < cap(w.buffer)/2:
+		if err := w.Flush(); err != nil {
+			return 0, err
+		}
+		w.buffer = append(w.buffer, p...)
+		return len(p), nil
+	}
+
+	if len(w.buffer) == 0 {
+		if n, err := w.out.Write(p); err != nil || n != len(p) {
+			w.err = errShortWrite(err)
+			return 0, w.err
+		}
+		return len(p), nil
+	}
+
+	buffers := net.Buffers{w.buffer, p}
+	size := int64(len(w.buffer) + len(p))
+	if n, err := buffers.WriteTo(w.out); err != nil || n != size {
+		w.err = errShortWrite(err)
+		return 0, w.err
+	}
+	w.buffer = w.buffer[:0]
+	return len(p), nil
+}
+
+// Grow flushes the buffer if it lacks room for n more bytes. It never enlarges the
+// buffer past its configured size, larger writes bypass it instead.
+func (w *bufferedWriter) Grow(n int) {
+	if n > cap(w.buffer)-len(w.buffer) && len(w.buffer) > 0 {
+		w.Flush()
+	}
+}
+
+func (w *bufferedWriter) AvailableBuffer() []byte {
+	return w.buffer[len(w.buffer):]
+}
+
+// Flush writes the buffered bytes to the underlying writer.
+func (w *bufferedWriter) Flush() error {
+	if w.err != nil || len(w.buffer) == 0 {
+		return w.err
+	}
+	if n, err := w.out.Write(w.buffer); err != nil || n != len(w.buffer) {
+		w.err = errShortWrite(err)
+		return w.err
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func errShortWrite(err error) error {
+	if err == nil {
+		return io.ErrShortWrite
+	}
+	return err
+}
+
+// ------------------------------------------------------------------------------
+
+// writeFramed writes a uvarint length followed by the value encoded with the codec,
+// encoding the body only once and straight to the output when the codec can size it
+// up front. Otherwise buffers get a reserved length byte that is back-patched, and
+// other writers get the body from a pooled buffer.
+func (e *Encoder) writeFramed(codec Codec, rv reflect.Value) error {
+	if e.err != nil {
+		return e.err
+	}
+	if size, ok := wireSize(codec, rv); ok {
+		e.WriteUvarint(uint64(size))
+		return e.encodeBody(codec, rv)
+	}
+	if out, ok := e.out.(*bytes.Buffer); ok {
+		start := out.Len()
+		out.WriteByte(0)
+		if err := e.encodeBody(codec, rv); err != nil {
+			return err
+		}
+
+		// Move the body along when its length does not fit in the reserved byte
+		size := out.Len() - start - 1
+		if n := uvarintSize(uint64(size)); n > 1 {
+			out.Write(e.scratch[:n-1])
+			b := out.Bytes()[start:]
+			copy(b[n:], b[1:1+size])
+		}
+		binary.PutUvarint(out.Bytes()[start:], uint64(size))
+		return nil
+	}
+
+	state := marshalBuffers.Get().(*marshalState)
+	state.Reset()
+	state.encoder.Reset(&state.Buffer)
+	err := state.encoder.encodeBody(codec, rv)
+	if err == nil {
+		e.WriteUvarint(uint64(state.Len()))
+		e.Write(state.Bytes())
+		err = e.err
+	}
+	if state.Cap() > maxFramedBuffer {
+		state.Buffer = bytes.Buffer{}
+	}
+	marshalBuffers.Put(state)
+	return err
+}
+
+func (e *Encoder) encodeBody(codec Codec, rv reflect.Value) error {
+	if err := codec.EncodeTo(e, rv); err != nil {
+		return err
+	}
+	return e.err
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"unsafe"
 
@@ -151,3 +152,143 @@ func TestEncoderErrorPaths(t *testing.T) {
 	large := reflect.New(reflect.ArrayOf(1<<20+1, reflect.TypeFor[byte]())).Elem()
 	assert.Equal(t, 64, marshalCapacity(large))
 }
+
+// countingWriter records the size of every write made to it.
+type countingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestBufferedEncoder(t *testing.T) {
+	var expect []byte
+	var out countingWriter
+	e := NewBufferedEncoder(&out, 64)
+	for i := range 10 {
+		v := &pathItem{Name: "item", Count: i}
+		b, err := Marshal(v)
+		assert.NoError(t, err)
+		expect = append(expect, b...)
+		assert.NoError(t, e.Encode(v))
+	}
+
+	// Nothing reaches the writer until the buffer fills up or is flushed
+	assert.Equal(t, 60, len(expect))
+	assert.Empty(t, out.writes)
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, []int{60}, out.writes)
+	assert.Equal(t, expect, out.Bytes())
+
+	// The long string is written along with the buffered bytes
+	assert.NoError(t, e.Encode(&pathItem{Name: "item"}))
+	assert.NoError(t, e.Encode(&pathItem{Name: strings.Repeat("x", 60)}))
+	assert.Equal(t, []int{60, 7, 60}, out.writes)
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, []int{60, 7, 60, 1}, out.writes)
+	assert.NoError(t, e.Flush())
+	assert.Len(t, out.writes, 4)
+}
+
+func TestBufferedEncoderLarge(t *testing.T) {
+	type blob struct {
+		ID   int
+		Data []byte
+	}
+
+	in := blob{ID: 1, Data: bytes.Repeat([]byte{'x'}, 100)}
+	expect, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	// The large field is written along with the buffered bytes, without being copied
+	var out countingWriter
+	e := NewBufferedEncoder(&out, 64)
+	assert.NoError(t, e.Encode(&in))
+	assert.Equal(t, []int{2, 100}, out.writes)
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, expect, out.Bytes())
+}
+
+func TestBufferedEncoderErrors(t *testing.T) {
+	e := NewBufferedEncoder(errorWriter{}, 0)
+	assert.NoError(t, e.Encode(&pathItem{Name: "item"}))
+	assert.Equal(t, io.ErrClosedPipe, e.Flush())
+	assert.Equal(t, io.ErrClosedPipe, e.Encode(&pathItem{Name: "item"}))
+
+	e = NewBufferedEncoder(nil, 0)
+	assert.Error(t, e.Encode(&pathItem{}))
+	assert.Error(t, e.Flush())
+
+	// Flushing an encoder that is not buffered does nothing
+	assert.NoError(t, NewEncoder(io.Discard).Flush())
+}
+
+func TestBufferedEncoderReset(t *testing.T) {
+	var first, second countingWriter
+	e := NewBufferedEncoder(&first, 64)
+	assert.NoError(t, e.Encode(&pathItem{Name: "item"}))
+
+	// The unflushed bytes are discarded, but the encoder stays buffered
+	e.Reset(&second)
+	assert.NoError(t, e.Encode(&pathItem{Name: "item"}))
+	assert.Empty(t, second.writes)
+	assert.NoError(t, e.Flush())
+	assert.Empty(t, first.writes)
+	assert.Equal(t, []int{6}, second.writes)
+
+	e.Reset(nil)
+	assert.Error(t, e.Flush())
+	e.Reset(&first)
+	assert.NoError(t, e.Encode(&pathItem{Name: "item"}))
+	assert.Empty(t, first.writes)
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, []int{6}, first.writes)
+}
+
+func TestBufferedWriterGrow(t *testing.T) {
+	var out countingWriter
+	w := newBufferedWriter(&out, 64)
+	w.Write([]byte("abc"))
+	w.Grow(10)
+	assert.Equal(t, 61, cap(w.AvailableBuffer()))
+	assert.Empty(t, out.writes)
+
+	// The buffered bytes are flushed, but the buffer keeps its size
+	w.Grow(100)
+	assert.Equal(t, []int{3}, out.writes)
+	assert.Equal(t, 64, cap(w.AvailableBuffer()))
+	w.Write(bytes.Repeat([]byte{'x'}, 100))
+	assert.Equal(t, []int{3, 100}, out.writes)
+
+	// Encoding a large slice does not grow the buffer to the size of the payload
+	in := make([]uint64, 100000)
+	for i := range in {
+		in[i] = uint64(i) << 20
+	}
+	expect, err := Marshal(in)
+	assert.NoError(t, err)
+
+	var large bytes.Buffer
+	e := NewBufferedEncoder(struct{ io.Writer }{&large}, 4096)
+	assert.NoError(t, e.Encode(in))
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, 4096, cap(e.out.(*bufferedWriter).buffer))
+	assert.Equal(t, expect, large.Bytes())
+}
+
+func TestMarshalToBuffered(t *testing.T) {
+	in := make([]pathItem, 100)
+	for i := range in {
+		in[i] = pathItem{Name: "item", Count: i}
+	}
+	expect, err := Marshal(in)
+	assert.NoError(t, err)
+
+	var out countingWriter
+	assert.NoError(t, MarshalTo(in, struct{ io.Writer }{&out}))
+	assert.Equal(t, []int{len(expect)}, out.writes)
+	assert.Equal(t, expect, out.Bytes())
+}
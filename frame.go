@@ -0,0 +1,233 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the maximum size of the frame writer
+// or reader.
+var ErrFrameTooLarge = errors.New("binary: frame exceeds maximum size")
+
+// ErrCorruptFrame is returned by a frame reader verifying checksums when the input does
+// not hold a valid frame. The reader skips to the next frame marker, so reading can go on.
+var ErrCorruptFrame = errors.New("binary: corrupt frame")
+
+const (
+	defaultMaxFrame = 4 << 20               // maximum frame size when none is given
+	frameHeader     = binary.MaxVarintLen64 // bytes reserved for the length of a frame
+)
+
+var emptyHeader [frameHeader + len(frameMarker)]byte
+
+// frameMarker starts every frame when checksums are enabled, for the reader to find the
+// next frame after a corrupted one.
+var frameMarker = [4]byte{0xf3, 0x7a, 0xb1, 0x9e}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func frameLimit(maxSize int) int {
+	if maxSize <= 0 {
+		return defaultMaxFrame
+	}
+	return maxSize
+}
+
+// ------------------------------------------------------------------------------
+
+// FrameWriter writes each message as a frame made of the uvarint length of the payload
+// followed by the payload, with a single write per frame.
+type FrameWriter struct {
+	out     io.Writer
+	max     int
+	buffer  bytes.Buffer
+	encoder Encoder
+	checked bool // frames carry a marker and a checksum
+}
+
+// NewFrameWriter returns a frame writer rejecting frames larger than maxSize bytes, or
+// than 4MB if the size is not positive.
+func NewFrameWriter(w io.Writer, maxSize int) *FrameWriter {
+	fw := &FrameWriter{out: w, max: frameLimit(maxSize)}
+	fw.encoder.Reset(&fw.buffer)
+	return fw
+}
+
+// Checksum makes the writer start each frame with a marker and end it with the CRC32C of
+// its length and payload, for a reader verifying checksums to detect corrupted frames and
+// resynchronize after them.
+func (w *FrameWriter) Checksum() {
+	w.checked = true
+}
+
+// Encode writes the encoding of v as a frame.
+func (w *FrameWriter) Encode(v any) error {
+	w.buffer.Reset()
+	w.buffer.Write(emptyHeader[:])
+	if err := w.encoder.Encode(v); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// WriteFrame writes an already encoded payload as a frame.
+func (w *FrameWriter) WriteFrame(payload []byte) error {
+	w.buffer.Reset()
+	w.buffer.Write(emptyHeader[:])
+	w.buffer.Write(payload)
+	return w.flush()
+}
+
+// flush writes the frame held in the buffer, filling in the header reserved before it.
+func (w *FrameWriter) flush() error {
+	if w.out == nil || isNilInterface(w.out) {
+		return errNilWriter
+	}
+	b := w.buffer.Bytes()
+	size := len(b) - len(emptyHeader)
+	if size > w.max {
+		return ErrFrameTooLarge
+	}
+	start := len(emptyHeader) - uvarintSize(uint64(size))
+	binary.PutUvarint(b[start:], uint64(size))
+	if w.checked {
+		var sum [4]byte
+		binary.LittleEndian.PutUint32(sum[:], crc32.Checksum(b[start:], castagnoli))
+		w.buffer.Write(sum[:])
+		b = w.buffer.Bytes()
+		start -= len(frameMarker)
+		copy(b[start:], frameMarker[:])
+	}
+	_, err := w.out.Write(b[start:])
+	return err
+}
+
+// ------------------------------------------------------------------------------
+
+// FrameReader reads the frames written by a FrameWriter. Each frame is read whole before
+// being decoded from memory, so it must hold exactly one value.
+type FrameReader struct {
+	reader  *streamReader
+	decoder *Decoder
+	max     int
+	checked bool // frames carry a marker and a checksum
+}
+
+// NewFrameReader returns a frame reader rejecting frames larger than maxSize bytes, or
+// than 4MB if the size is not positive, before reading them.
+func NewFrameReader(r io.Reader, maxSize int) *FrameReader {
+	if r == nil || isNilInterface(r) {
+		r = bytes.NewReader(nil)
+	}
+	fr := &FrameReader{
+		reader:  newStreamReader(r),
+		decoder: NewDecoder(nil),
+		max:     frameLimit(maxSize),
+	}
+	fr.decoder.DisallowTrailingData()
+	return fr
+}
+
+// Checksum makes the reader expect the frames written by a FrameWriter with checksums
+// enabled. A frame failing verification, including one cut short by the end of the input,
+// makes ReadFrame return ErrCorruptFrame and skip to the next marker.
+func (r *FrameReader) Checksum() {
+	r.checked = true
+}
+
+// ReadFrame reads the next frame and returns its payload, which is only valid until the
+// next frame is read. It returns io.EOF when the input ends between frames.
+func (r *FrameReader) ReadFrame() ([]byte, error) {
+	r.reader.release()
+	if r.checked {
+		return r.readChecked()
+	}
+
+	size, err := r.reader.ReadUvarint()
+	switch {
+	case err != nil:
+		return nil, err
+	case size > uint64(r.max):
+		return nil, ErrFrameTooLarge
+	}
+
+	payload, err := r.reader.Slice(int(size))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return payload, err
+}
+
+// Decode reads the next frame and decodes it into v. Values of the nocopy types point
+// into the frame, and are only valid until the next frame is read.
+func (r *FrameReader) Decode(v any) error {
+	payload, err := r.ReadFrame()
+	if err != nil {
+		return err
+	}
+	r.decoder.slice.Reset(payload)
+	return r.decoder.Decode(v)
+}
+
+// readChecked verifies the frame at the current position without consuming it, so that
+// the search for the next marker can start right after the current one if it is invalid.
+func (r *FrameReader) readChecked() ([]byte, error) {
+	s := r.reader
+	header, err := s.peek(len(frameMarker) + binary.MaxVarintLen64)
+	switch {
+	case len(header) == 0:
+		return nil, err
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return nil, err
+	}
+
+	if bytes.HasPrefix(header, frameMarker[:]) {
+		size, n := binary.Uvarint(header[len(frameMarker):])
+		if n > 0 && size <= uint64(r.max) {
+			end := len(frameMarker) + n + int(size)
+			frame, err := s.peek(end + 4)
+			switch {
+			case err != nil && err != io.ErrUnexpectedEOF && err != io.EOF:
+				return nil, err
+			case len(frame) == end+4 && crc32.Checksum(frame[len(frameMarker):end], castagnoli) == binary.LittleEndian.Uint32(frame[end:]):
+				s.offset += end + 4
+				s.pinned = true
+				return frame[len(frameMarker)+n : end : end], nil
+			}
+		}
+	}
+
+	if err := r.resync(); err != nil {
+		return nil, err
+	}
+	return nil, ErrCorruptFrame
+}
+
+// resync skips past the current position to the next frame marker, or to the end of the input.
+func (r *FrameReader) resync() error {
+	s := r.reader
+	s.offset++
+	for {
+		if i := bytes.Index(s.buffer[s.offset:], frameMarker[:]); i >= 0 {
+			s.offset += i
+			return nil
+		}
+
+		// Keep the bytes that could be the start of a marker, and read more
+		s.offset = max(s.offset, len(s.buffer)-len(frameMarker)+1)
+		switch err := s.fill(len(s.buffer) - s.offset + 1); err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			s.offset = len(s.buffer)
+			return nil
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrames(t *testing.T) {
+	var out countingWriter
+	w := NewFrameWriter(&out, 0)
+	for i := range 100 {
+		assert.NoError(t, w.Encode(&pathItem{Name: strings.Repeat("x", i*3), Count: i}))
+	}
+
+	// Each frame is written at once, with a uvarint length
+	assert.Len(t, out.writes, 100)
+	assert.Equal(t, []byte{2, 0, 0}, out.Bytes()[:3])
+
+	for name, source := range map[string]io.Reader{
+		"buffered": bytes.NewReader(out.Bytes()),
+		"network":  newNetworkSource(out.Bytes()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := NewFrameReader(source, 0)
+			for i := range 100 {
+				var item pathItem
+				assert.NoError(t, r.Decode(&item))
+				assert.Equal(t, pathItem{Name: strings.Repeat("x", i*3), Count: i}, item)
+			}
+			assert.Equal(t, io.EOF, r.Decode(new(pathItem)))
+		})
+	}
+}
+
+func TestFramesRaw(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf, 0)
+	assert.NoError(t, w.WriteFrame([]byte("hello")))
+	assert.NoError(t, w.WriteFrame(nil))
+	assert.NoError(t, w.WriteFrame(bytes.Repeat([]byte{'x'}, 200)))
+	assert.Equal(t, "\x05hello\x00\xc8\x01", buf.String()[:9])
+
+	r := NewFrameReader(&buf, 0)
+	frame, err := r.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(frame))
+	frame, err = r.ReadFrame()
+	assert.NoError(t, err)
+	assert.Empty(t, frame)
+	frame, err = r.ReadFrame()
+	assert.NoError(t, err)
+	assert.Len(t, frame, 200)
+	_, err = r.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFramesConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		w := NewFrameWriter(client, 0)
+		for i := range 10 {
+			w.Encode(&pathItem{Name: "item", Count: i})
+		}
+		client.Close()
+	}()
+
+	r := NewFrameReader(server, 0)
+	for i := range 10 {
+		var item pathItem
+		assert.NoError(t, r.Decode(&item))
+		assert.Equal(t, i, item.Count)
+	}
+	assert.Equal(t, io.EOF, r.Decode(new(pathItem)))
+}
+
+func TestFramesErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf, 8)
+	assert.Equal(t, ErrFrameTooLarge, w.Encode(&pathItem{Name: "too long for the frame"}))
+	assert.Equal(t, ErrFrameTooLarge, w.WriteFrame(make([]byte, 9)))
+	assert.NoError(t, w.WriteFrame(make([]byte, 8)))
+	assert.Equal(t, 9, buf.Len())
+	assert.Equal(t, errNilWriter, NewFrameWriter(nil, 0).WriteFrame(nil))
+
+	// Oversized frames are rejected before their payload is read
+	large := NewFrameWriter(&buf, 0)
+	assert.NoError(t, large.WriteFrame(make([]byte, 100)))
+	r := NewFrameReader(&buf, 8)
+	_, err := r.ReadFrame()
+	assert.NoError(t, err)
+	_, err = r.ReadFrame()
+	assert.Equal(t, ErrFrameTooLarge, err)
+
+	// Truncated frames and frames holding more than one value
+	for input, expect := range map[string]error{
+		"\x05hel":          io.ErrUnexpectedEOF,
+		"\x80":             io.ErrUnexpectedEOF,
+		"\x01\x05":         io.EOF,
+		"\x03\x00\x02\x00": ErrTrailingData,
+	} {
+		assert.Equal(t, expect, NewFrameReader(strings.NewReader(input), 0).Decode(new(pathItem)), input)
+	}
+	assert.Equal(t, io.EOF, NewFrameReader(nil, 0).Decode(new(pathItem)))
+}
+
+func TestFramesChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf, 0)
+	w.Checksum()
+	for i := range 10 {
+		assert.NoError(t, w.Encode(&pathItem{Name: "item", Count: i}))
+	}
+	assert.Equal(t, frameMarker[:], buf.Bytes()[:4])
+	assert.Equal(t, 10*15, buf.Len())
+
+	for name, source := range map[string]io.Reader{
+		"buffered": bytes.NewReader(buf.Bytes()),
+		"network":  newNetworkSource(buf.Bytes()),
+		"bytes":    &oneByteReader{content: buf.Bytes()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := NewFrameReader(source, 0)
+			r.Checksum()
+			for i := range 10 {
+				var item pathItem
+				assert.NoError(t, r.Decode(&item))
+				assert.Equal(t, i, item.Count)
+			}
+			assert.Equal(t, io.EOF, r.Decode(new(pathItem)))
+		})
+	}
+}
+
+func TestFramesResync(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf, 0)
+	w.Checksum()
+	for i := range 10 {
+		assert.NoError(t, w.Encode(&pathItem{Name: "item", Count: i}))
+	}
+
+	corrupt := func(edit func(b []byte) []byte) []byte {
+		return edit(append([]byte{}, buf.Bytes()...))
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		expect []int // counts read, -1 for each corrupt frame
+	}{
+		{"payload", corrupt(func(b []byte) []byte {
+			b[15*3+7] ^= 0xff
+			return b
+		}), []int{0, 1, 2, -1, 4, 5, 6, 7, 8, 9}},
+		{"length", corrupt(func(b []byte) []byte {
+			b[15*3+4] = 0xff
+			return b
+		}), []int{0, 1, 2, -1, 4, 5, 6, 7, 8, 9}},
+		{"marker", corrupt(func(b []byte) []byte {
+			b[15*3] = 0
+			return b
+		}), []int{0, 1, 2, -1, 4, 5, 6, 7, 8, 9}},
+		{"garbage", corrupt(func(b []byte) []byte {
+			return append([]byte("garbage\xf3\x7a"), b...)
+		}), []int{-1, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"truncated", corrupt(func(b []byte) []byte {
+			return b[:len(b)-3]
+		}), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, -1}},
+		{"torn", corrupt(func(b []byte) []byte {
+			return append(b[:15*5+6], b[15*6:]...)
+		}), []int{0, 1, 2, 3, 4, -1, 6, 7, 8, 9}},
+	}
+
+	for _, tc := range tests {
+		for name, source := range map[string]io.Reader{
+			"buffered": bytes.NewReader(tc.input),
+			"network":  newNetworkSource(tc.input),
+		} {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				r := NewFrameReader(source, 0)
+				r.Checksum()
+
+				var counts []int
+				for {
+					var item pathItem
+					err := r.Decode(&item)
+					if err == io.EOF {
+						break
+					}
+
+					switch {
+					case err == ErrCorruptFrame:
+						counts = append(counts, -1)
+					case assert.NoError(t, err):
+						counts = append(counts, item.Count)
+					default:
+						return
+					}
+				}
+				assert.Equal(t, tc.expect, counts)
+			})
+		}
+	}
+}
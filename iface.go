@@ -0,0 +1,169 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var unions = struct {
+	sync.Mutex
+	byType map[reflect.Type][]interfaceArm
+}{byType: make(map[reflect.Type][]interfaceArm)}
+
+type interfaceArm struct {
+	tag   uint64
+	typ   reflect.Type // dynamic type stored in the interface
+	codec Codec        // codec of the value, or of the pointee for pointer types
+}
+
+// RegisterUnion declares the implementations of the interface T as the arms of a tagged
+// union, given as pairs of a tag and a prototype value, for example
+// RegisterUnion[Shape](1, Circle{}, 2, &Square{}). Fields of type T are then encoded
+// with the same framing as union structs, the tag being picked from the dynamic type.
+// It must be called before T is first encoded or decoded, and panics if the arguments
+// are invalid or T was already registered.
+func RegisterUnion[T any](pairs ...any) {
+	t := reflect.TypeFor[T]()
+	switch {
+	case t.Kind() != reflect.Interface:
+		panic("binary: cannot register union on non-interface type " + t.String())
+	case len(pairs) == 0 || len(pairs)%2 != 0:
+		panic("binary: union of " + t.String() + " must be given as tag and value pairs")
+	}
+
+	arms := make([]interfaceArm, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		tag, ok := unionTag(pairs[i])
+		if !ok {
+			panic("binary: invalid union tag " + strconv.Itoa(i/2+1) + " for " + t.String())
+		}
+		typ := reflect.TypeOf(pairs[i+1])
+		if typ == nil || !typ.Implements(t) {
+			panic("binary: union arm " + strconv.FormatUint(tag, 10) + " does not implement " + t.String())
+		}
+		for _, arm := range arms {
+			switch {
+			case arm.tag == tag:
+				panic("binary: duplicate union tag " + strconv.FormatUint(tag, 10) + " for " + t.String())
+			case arm.typ == typ:
+				panic("binary: duplicate union arm " + typ.String() + " for " + t.String())
+			}
+		}
+		arms = append(arms, interfaceArm{tag: tag, typ: typ})
+	}
+
+	unions.Lock()
+	defer unions.Unlock()
+	if _, ok := unions.byType[t]; ok {
+		panic("binary: union already registered for " + t.String())
+	}
+	unions.byType[t] = arms
+}
+
+func unionTag(v any) (uint64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), rv.Int() > 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), rv.Uint() > 0
+	default:
+		return 0, false
+	}
+}
+
+func scanInterface(t reflect.Type) (Codec, error) {
+	unions.Lock()
+	registered, ok := unions.byType[t]
+	unions.Unlock()
+	if !ok {
+		return nil, errors.New("binary: unsupported type " + t.String() + ", use RegisterUnion to declare its implementations")
+	}
+
+	c := &interfaceCodec{
+		arms:   make([]interfaceArm, len(registered)),
+		byTag:  make(map[uint64]int, len(registered)),
+		byType: make(map[reflect.Type]int, len(registered)),
+	}
+	for i, arm := range registered {
+		elem := arm.typ
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		codec, err := scanType(elem)
+		if err != nil {
+			return nil, err
+		}
+		arm.codec = codec
+		c.arms[i] = arm
+		c.byTag[arm.tag] = i
+		c.byType[arm.typ] = i
+	}
+	return c, nil
+}
+
+// interfaceCodec encodes an interface registered with RegisterUnion as a tagged union.
+type interfaceCodec struct {
+	arms   []interfaceArm
+	byTag  map[uint64]int
+	byType map[reflect.Type]int
+}
+
+func (c *interfaceCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	if rv.IsNil() {
+		e.WriteTagged(0, nil)
+		return e.err
+	}
+	value := rv.Elem()
+	i, ok := c.byType[value.Type()]
+	if !ok {
+		return errors.New("binary: " + value.Type().String() + " is not registered in the union of " + rv.Type().String())
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return errors.New("binary: cannot encode nil " + value.Type().String() + " in a union")
+		}
+		value = value.Elem()
+	}
+	e.WriteUvarint(c.arms[i].tag)
+	return e.writeFramed(c.arms[i].codec, value)
+}
+
+func (c *interfaceCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	tag, body, err := d.ReadTagged()
+	if err != nil {
+		return err
+	}
+	i, ok := c.byTag[tag]
+	if !ok {
+		rv.SetZero() // unknown arms are skipped
+		return nil
+	}
+
+	// Pointer arms reuse the value already held by the interface
+	arm := &c.arms[i]
+	var ptr reflect.Value
+	switch {
+	case arm.typ.Kind() != reflect.Pointer:
+		ptr = reflect.New(arm.typ)
+	case !rv.IsNil() && rv.Elem().Type() == arm.typ && !rv.Elem().IsNil():
+		ptr = rv.Elem()
+	default:
+		ptr = reflect.New(arm.typ.Elem())
+	}
+	rv.SetZero()
+	if err := decodeArm(d, arm.codec, body, ptr.Elem()); err != nil {
+		return err
+	}
+	if arm.typ.Kind() == reflect.Pointer {
+		rv.Set(ptr)
+	} else {
+		rv.Set(ptr.Elem())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shape interface {
+	Area() float64
+}
+
+type circle struct{ Radius float64 }
+type square struct{ Side float64 }
+type polygon struct{ Points []float64 }
+
+func (c circle) Area() float64        { return 3 * c.Radius * c.Radius }
+func (s *square) Area() float64       { return s.Side * s.Side }
+func (p polygon) Area() float64       { return 0 }
+func (p *unknownShape) Area() float64 { return 0 }
+
+type unknownShape struct{}
+
+type drawing struct {
+	Name   string
+	Shape  shape
+	Shapes []shape
+}
+
+func init() {
+	RegisterUnion[shape](1, circle{}, 2, &square{}, uint8(3), polygon{})
+}
+
+func TestInterfaceUnion(t *testing.T) {
+	in := drawing{
+		Name:   "a",
+		Shape:  &square{Side: 2},
+		Shapes: []shape{circle{Radius: 1}, nil, polygon{Points: []float64{1, 2}}, &square{Side: 3}},
+	}
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	var out drawing
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+
+	// Pointer arms are decoded into the value already held
+	held := &square{}
+	out.Shape = held
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.True(t, held == out.Shape)
+	assert.Equal(t, &square{Side: 2}, held)
+}
+
+func TestInterfaceUnionWire(t *testing.T) {
+	var s shape = circle{Radius: 2}
+	b, err := Marshal(&s)
+	assert.NoError(t, err)
+
+	// The framing is the one of the equivalent union struct
+	var union struct {
+		Circle *circle `binary:"1,union"`
+		Square *square `binary:"2,union"`
+	}
+	assert.NoError(t, Unmarshal(b, &union))
+	assert.Equal(t, &circle{Radius: 2}, union.Circle)
+
+	// Unknown tags are skipped and nil is written as tag 0
+	var buf []byte
+	buf, err = Marshal(&struct {
+		Other *circle `binary:"9,union"`
+	}{Other: &circle{}})
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(buf, &s))
+	assert.Nil(t, s)
+
+	b, err = Marshal(&s)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0}, b)
+}
+
+func TestInterfaceUnionErrors(t *testing.T) {
+	_, err := Marshal(&drawing{Shape: &unknownShape{}})
+	assert.Error(t, err)
+	_, err = Marshal(&drawing{Shape: (*square)(nil)})
+	assert.Error(t, err)
+
+	var unregistered struct{ Value any }
+	_, err = Marshal(&unregistered)
+	assert.Error(t, err)
+
+	assert.Error(t, Unmarshal([]byte{0, 1, 2, 5}, &drawing{}))
+	assert.Panics(t, func() { RegisterUnion[shape](4, &unknownShape{}) })
+	assert.Panics(t, func() { RegisterUnion[circle](1, circle{}) })
+	assert.Panics(t, func() { RegisterUnion[error]() })
+	assert.Panics(t, func() { RegisterUnion[error](1) })
+	assert.Panics(t, func() { RegisterUnion[error](0, &unknownShape{}) })
+	assert.Panics(t, func() { RegisterUnion[shape](-1, circle{}) })
+	assert.Panics(t, func() { RegisterUnion[any](1, circle{}, 1, polygon{}) })
+	assert.Panics(t, func() { RegisterUnion[any](1, circle{}, 2, circle{}) })
+	assert.Panics(t, func() { RegisterUnion[any]("1", circle{}) })
+	assert.Panics(t, func() { RegisterUnion[any](1, nil) })
+}
@@ -0,0 +1,41 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package byteorder records the byte order of the slices encoded by copying their memory.
+package byteorder
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// Flag is set on the length prefix when the payload was written by a big-endian host.
+// Little-endian hosts never set it, so their wire format is unchanged.
+const Flag = uint64(1) << 63
+
+// Native is Flag on a big-endian host, and zero otherwise.
+var Native = func() uint64 {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 0 {
+		return Flag
+	}
+	return 0
+}()
+
+// Swap reverses the byte order of every element of the given size, in place.
+func Swap(b []byte, size int) {
+	switch size {
+	case 2:
+		for i := 0; i+2 <= len(b); i += 2 {
+			binary.LittleEndian.PutUint16(b[i:], binary.BigEndian.Uint16(b[i:]))
+		}
+	case 4:
+		for i := 0; i+4 <= len(b); i += 4 {
+			binary.LittleEndian.PutUint32(b[i:], binary.BigEndian.Uint32(b[i:]))
+		}
+	case 8:
+		for i := 0; i+8 <= len(b); i += 8 {
+			binary.LittleEndian.PutUint64(b[i:], binary.BigEndian.Uint64(b[i:]))
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package fields tells the subpackages which fields of a struct are encoded by its codec,
+// so that they can walk a value the way it is decoded.
+package fields
+
+import "reflect"
+
+// Encoded returns the indices of the fields of the struct type t that its codec writes,
+// union arms included, even unexported ones such as the arm of a OneOf. It returns nil
+// when t is not encoded field by field, such as a type with its own codec. It is set by
+// the binary package when it is initialized.
+var Encoded func(t reflect.Type) ([]int, error)
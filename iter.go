@@ -0,0 +1,90 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+)
+
+// Values returns an iterator over the values of type T concatenated in r, which stops at
+// the end of the input or after yielding the first error. The same T is decoded into at
+// each step, and slices read from r are only valid until the next step.
+func Values[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		d := NewDecoder(r)
+		var value T
+		for {
+			start := d.InputOffset()
+			err := d.Decode(&value)
+			switch {
+			case err == io.EOF && (d.InputOffset() > start || d.source != nil && d.source.Len() > 0):
+				err = io.ErrUnexpectedEOF
+			case err == io.EOF:
+				return
+			}
+
+			if !yield(value, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Elements returns an iterator over the elements of the []T encoded in b, decoding them
+// one at a time into the same T instead of decoding the whole slice. It stops after
+// yielding the first error.
+func Elements[T any](b []byte) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var value T
+		codec, err := scan(reflect.TypeFor[[]T]())
+		if err != nil {
+			yield(value, err)
+			return
+		}
+
+		elem, _, ok := elementCodec(codec)
+		if !ok {
+			yield(value, errors.New("binary: cannot iterate over the elements of "+reflect.TypeFor[[]T]().String()))
+			return
+		}
+
+		d := decoders.Get().(*Decoder)
+		d.slice.Reset(b)
+		defer release(d)
+
+		l, err := d.ReadUvarint()
+		if err != nil {
+			yield(value, err)
+			return
+		}
+		n, err := decodeLength(l)
+		if err == nil {
+			err = d.ensureElements(n, wireMinBytes(elem))
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			yield(value, err)
+			return
+		}
+
+		rv := reflect.ValueOf(&value).Elem()
+		for range n {
+			// Start a fresh arena for each element, as Decode does, so that the map keys
+			// kept from one element do not share their memory with those of the next
+			d.arena = nil
+			err := elem.DecodeTo(d, rv)
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if !yield(value, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValues(t *testing.T) {
+	var buf bytes.Buffer
+	for i := range 50 {
+		assert.NoError(t, MarshalTo(&pathItem{Name: strings.Repeat("x", i), Count: i}, &buf))
+	}
+
+	for name, source := range map[string]func() io.Reader{
+		"bytes":   func() io.Reader { return bytes.NewBuffer(buf.Bytes()) },
+		"reader":  func() io.Reader { return bytes.NewReader(buf.Bytes()) },
+		"bufio":   func() io.Reader { return bufio.NewReader(bytes.NewReader(buf.Bytes())) },
+		"network": func() io.Reader { return newNetworkSource(buf.Bytes()) },
+		"onebyte": func() io.Reader { return &oneByteReader{content: buf.Bytes()} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var count int
+			for v, err := range Values[pathItem](source()) {
+				assert.NoError(t, err)
+				assert.Equal(t, pathItem{Name: strings.Repeat("x", count), Count: count}, v)
+				count++
+			}
+			assert.Equal(t, 50, count)
+
+			// Stops early when the loop breaks
+			count = 0
+			for range Values[pathItem](source()) {
+				if count++; count == 3 {
+					break
+				}
+			}
+			assert.Equal(t, 3, count)
+		})
+	}
+}
+
+func TestValuesTruncated(t *testing.T) {
+	b, err := Marshal(&pathItem{Name: "hello", Count: 300})
+	assert.NoError(t, err)
+	input := append(append([]byte{}, b...), b[:len(b)-1]...)
+
+	for name, source := range map[string]io.Reader{
+		"bytes":   bytes.NewBuffer(input),
+		"reader":  bytes.NewReader(input),
+		"network": newNetworkSource(input),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var errs []error
+			for _, err := range Values[pathItem](source) {
+				errs = append(errs, err)
+			}
+			assert.Equal(t, []error{nil, io.ErrUnexpectedEOF}, errs)
+		})
+	}
+
+	for range Values[pathItem](bytes.NewReader(nil)) {
+		assert.Fail(t, "empty input yields no value")
+	}
+}
+
+func TestElements(t *testing.T) {
+	items := make([]pathItem, 100)
+	for i := range items {
+		items[i] = pathItem{Name: strings.Repeat("x", i%7), Count: i}
+	}
+	b, err := Marshal(items)
+	assert.NoError(t, err)
+
+	var out []pathItem
+	for v, err := range Elements[pathItem](b) {
+		assert.NoError(t, err)
+		out = append(out, v)
+	}
+	assert.Equal(t, items, out)
+
+	// The other slice encodings
+	assertElements(t, []string{"a", "bc", ""})
+	assertElements(t, []byte{1, 2, 3})
+	assertElements(t, []bool{true, false, true})
+	assertElements(t, []float64{1.5, -2.5})
+	assertElements(t, []uint32{1, 300, 70000})
+	assertElements(t, []int16{-1, 0, 1000})
+	assertElements(t, []*pathItem{nil, {Name: "p", Count: 1}})
+	assertElements(t, []map[string]int{{"a": 1}, {"b": 2}})
+	assertElements(t, []payload{{Text: &textPayload{Msg: "hi"}}, {Image: &imagePayload{Width: 1, Height: 2}}})
+}
+
+func assertElements[T any](t *testing.T, items []T) {
+	b, err := Marshal(items)
+	assert.NoError(t, err)
+
+	var i int
+	for v, err := range Elements[T](b) {
+		assert.NoError(t, err)
+		assert.Equal(t, items[i], v)
+		i++
+	}
+	assert.Equal(t, len(items), i)
+}
+
+func TestElementsErrors(t *testing.T) {
+	b, err := Marshal([]pathItem{{"a", 1}, {"b", 2}})
+	assert.NoError(t, err)
+
+	var errs []error
+	for _, err := range Elements[pathItem](b[:len(b)-1]) {
+		errs = append(errs, err)
+	}
+	assert.Len(t, errs, 2)
+	assert.NoError(t, errs[0])
+	assert.Equal(t, io.ErrUnexpectedEOF, errs[1])
+
+	// A length that the remaining bytes cannot hold is rejected before decoding
+	errs = errs[:0]
+	for _, err := range Elements[pathItem]([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 1, 'a', 1}) {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, []error{io.ErrUnexpectedEOF}, errs)
+
+	for _, err := range Elements[pathItem](nil) {
+		assert.Equal(t, io.EOF, err)
+	}
+	for _, err := range Elements[chan int]([]byte{1}) {
+		assert.Error(t, err)
+	}
+}
+
+func TestElementsAllocs(t *testing.T) {
+	items := make([]pathItem, 1000)
+	for i := range items {
+		items[i] = pathItem{Name: "item", Count: i}
+	}
+	b, err := Marshal(items)
+	assert.NoError(t, err)
+
+	var sum int
+	allocs := testing.AllocsPerRun(10, func() {
+		for v := range Elements[pathItem](b) {
+			sum += v.Count
+		}
+	})
+	assert.True(t, allocs <= 2, "%v allocs", allocs)
+}
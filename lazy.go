@@ -0,0 +1,108 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Lazy holds a value of T that is only decoded when first accessed. It is encoded as
+// the length-prefixed encoding of T; decoding keeps a copy of those bytes, which are
+// written back unchanged until the value is accessed with Get or replaced with Set.
+type Lazy[T any] struct {
+	raw   []byte // encoding of the value, if valid
+	valid bool   // whether raw holds the current encoding
+	value *T     // decoded or assigned value
+}
+
+// NewLazy returns a Lazy holding the value.
+func NewLazy[T any](v *T) Lazy[T] {
+	return Lazy[T]{value: v}
+}
+
+// Get decodes the value on first access and returns it, caching the result. Since the
+// value may be changed through the pointer, it is encoded again from then on instead of
+// writing back the original bytes.
+func (l *Lazy[T]) Get() (*T, error) {
+	if l.value == nil {
+		v := new(T)
+		if l.valid {
+			if err := Unmarshal(l.raw, v); err != nil {
+				return nil, err
+			}
+		}
+		l.value = v
+	}
+	l.valid = false
+	return l.value, nil
+}
+
+// Set replaces the value, which is encoded in place of the original bytes. A nil value
+// is encoded as the zero value of T.
+func (l *Lazy[T]) Set(v *T) {
+	l.value = v
+	l.valid = false
+}
+
+// Raw returns the encoding of the value if it is still the one that was decoded.
+func (l *Lazy[T]) Raw() ([]byte, bool) {
+	return l.raw, l.valid
+}
+
+func (*Lazy[T]) scanCodec() (Codec, error) {
+	codec, err := scanType(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	return &lazyCodec{elem: reflect.TypeFor[T](), codec: codec}, nil
+}
+
+// lazyState has the layout of every Lazy, giving the codec access to its fields without
+// knowing T.
+type lazyState struct {
+	raw   []byte
+	valid bool
+	value unsafe.Pointer // the *T field
+}
+
+type lazyCodec struct {
+	elem  reflect.Type
+	codec Codec
+}
+
+func (c *lazyCodec) state(rv reflect.Value) *lazyState {
+	if !rv.CanAddr() {
+		tmp := reflect.New(rv.Type()).Elem()
+		tmp.Set(rv)
+		rv = tmp
+	}
+	return (*lazyState)(unsafe.Pointer(rv.UnsafeAddr()))
+}
+
+func (c *lazyCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	l := c.state(rv)
+	switch {
+	case l.valid:
+		e.WriteUvarint(uint64(len(l.raw)))
+		e.Write(l.raw)
+		return e.err
+	case l.value == nil:
+		return e.writeFramed(c.codec, reflect.New(c.elem).Elem())
+	default:
+		return e.writeFramed(c.codec, reflect.NewAt(c.elem, l.value).Elem())
+	}
+}
+
+func (c *lazyCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	body, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+	l := c.state(rv)
+	l.raw = append(make([]byte, 0, len(body)), body...) // copies of the Lazy may share the old one
+	l.valid = true
+	l.value = nil
+	return nil
+}
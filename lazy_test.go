@@ -0,0 +1,117 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lazyEnvelope struct {
+	ID   uint64
+	Body Lazy[docV2]
+	Tail string
+}
+
+func TestLazy(t *testing.T) {
+	in := lazyEnvelope{ID: 1, Body: NewLazy(&docV2{Title: "a", Body: "b"}), Tail: "c"}
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	// The body is framed like a length-prefixed value
+	body, err := Marshal(&docV2{Title: "a", Body: "b"})
+	assert.NoError(t, err)
+	assert.True(t, bytes.Contains(b, append([]byte{byte(len(body))}, body...)))
+
+	var out lazyEnvelope
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, "c", out.Tail)
+	raw, ok := out.Body.Raw()
+	assert.True(t, ok)
+	assert.Equal(t, body, raw)
+
+	// Bodies that were not accessed are forwarded byte for byte, even once the input is reused
+	original := bytes.Clone(b)
+	clear(b)
+	forwarded, err := Marshal(out)
+	assert.NoError(t, err)
+	assert.Equal(t, original, forwarded)
+
+	v, err := out.Body.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, &docV2{Title: "a", Body: "b"}, v)
+	again, err := out.Body.Get()
+	assert.NoError(t, err)
+	assert.True(t, v == again)
+
+	// Changes made through Get are encoded
+	v.Title = "edited"
+	_, ok = out.Body.Raw()
+	assert.False(t, ok)
+	b, err = Marshal(&out)
+	assert.NoError(t, err)
+
+	var edited lazyEnvelope
+	assert.NoError(t, Unmarshal(b, &edited))
+	v, err = edited.Body.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, &docV2{Title: "edited", Body: "b"}, v)
+
+	// Once set, the new value is encoded instead
+	out.Body.Set(&docV2{Title: "x"})
+	b, err = Marshal(&out)
+	assert.NoError(t, err)
+
+	var next lazyEnvelope
+	assert.NoError(t, Unmarshal(b, &next))
+	v, err = next.Body.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, &docV2{Title: "x"}, v)
+}
+
+func TestLazyZero(t *testing.T) {
+	var zero Lazy[docV2]
+	v, err := zero.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, &docV2{}, v)
+
+	zero.Set(nil)
+	b, err := Marshal(&zero)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2, 0, 0}, b)
+
+	var out Lazy[docV2]
+	assert.NoError(t, Unmarshal(b, &out))
+	v, err = out.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, &docV2{}, v)
+}
+
+func TestLazyErrors(t *testing.T) {
+	var out Lazy[docV2]
+	assert.Error(t, Unmarshal([]byte{5, 1}, &out))
+	assert.NoError(t, Unmarshal([]byte{1, 5}, &out))
+	_, err := out.Get()
+	assert.Error(t, err)
+
+	_, err = Marshal(&Lazy[chan int]{})
+	assert.Error(t, err)
+}
+
+func TestLazyAllocs(t *testing.T) {
+	b, err := Marshal(&lazyEnvelope{ID: 1, Body: NewLazy(&docV2{Title: "a"})})
+	assert.NoError(t, err)
+
+	var out lazyEnvelope
+	assert.NoError(t, Unmarshal(b, &out))
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		_ = enc.Encode(&out)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
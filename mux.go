@@ -0,0 +1,62 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// ErrUnknownTag is returned by Mux.Dispatch when no handler is registered for a tag.
+var ErrUnknownTag = errors.New("binary: no handler for union tag")
+
+// Mux routes union frames to the handler registered for their tag, decoding only the
+// arm that the handler asks for. Handlers must be registered before dispatching; the
+// zero value is ready to use.
+type Mux struct {
+	handlers map[uint64]func(body []byte) error
+}
+
+// Handle registers a handler for the tag, which receives the arm body decoded into a
+// new T. It panics if the tag is zero, already registered or T cannot be decoded.
+func Handle[T any](m *Mux, tag uint64, fn func(*T) error) {
+	switch {
+	case tag == 0:
+		panic("binary: union tag must be greater than zero")
+	case m.handlers[tag] != nil:
+		panic("binary: duplicate handler for union tag " + strconv.FormatUint(tag, 10))
+	}
+	codec, err := scan(reflect.TypeFor[T]())
+	if err != nil {
+		panic(err)
+	}
+	if m.handlers == nil {
+		m.handlers = make(map[uint64]func([]byte) error)
+	}
+	m.handlers[tag] = func(body []byte) error {
+		v := new(T)
+		d := borrowDecoder(body, 0)
+		err := codec.DecodeTo(d, reflect.ValueOf(v).Elem())
+		release(d)
+		if err != nil {
+			return err
+		}
+		return fn(v)
+	}
+}
+
+// Dispatch reads the union frame in b and calls the handler registered for its tag,
+// returning ErrUnknownTag if there is none.
+func (m *Mux) Dispatch(b []byte) error {
+	tag, body, err := PeekTag(b)
+	if err != nil {
+		return err
+	}
+	handler := m.handlers[tag]
+	if handler == nil {
+		return ErrUnknownTag
+	}
+	return handler(body)
+}
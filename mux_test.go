@@ -0,0 +1,61 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMux(t *testing.T) {
+	var (
+		mux   Mux
+		texts []string
+		image *imagePayload
+	)
+	Handle(&mux, 1, func(v *textPayload) error {
+		texts = append(texts, v.Msg)
+		return nil
+	})
+	Handle(&mux, 2, func(v *imagePayload) error {
+		image = v
+		return io.ErrClosedPipe
+	})
+
+	b, err := Marshal(payload{Text: &textPayload{Msg: "hi"}})
+	assert.NoError(t, err)
+	assert.NoError(t, mux.Dispatch(b))
+	assert.Equal(t, []string{"hi"}, texts)
+
+	// Errors from the handler are returned as they are
+	b, err = Marshal(payload{Image: &imagePayload{Width: 3, Height: 4}})
+	assert.NoError(t, err)
+	assert.Equal(t, io.ErrClosedPipe, mux.Dispatch(b))
+	assert.Equal(t, &imagePayload{Width: 3, Height: 4}, image)
+
+	b, err = Marshal(payload{})
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(mux.Dispatch(b), ErrUnknownTag))
+	assert.Error(t, mux.Dispatch([]byte{1, 5, 0}))
+	assert.Error(t, mux.Dispatch(nil))
+
+	// A malformed body is reported before reaching the handler
+	assert.Error(t, mux.Dispatch([]byte{1, 1, 5}))
+	assert.Equal(t, []string{"hi"}, texts)
+}
+
+func TestMuxHandle(t *testing.T) {
+	var mux Mux
+	handler := func(*textPayload) error { return nil }
+	Handle(&mux, 1, handler)
+	assert.Panics(t, func() { Handle(&mux, 0, handler) })
+	assert.Panics(t, func() { Handle(&mux, 1, handler) })
+	assert.Panics(t, func() { Handle(&mux, 2, func(*chan int) error { return nil }) })
+
+	var empty Mux
+	assert.Equal(t, ErrUnknownTag, empty.Dispatch([]byte{1, 0}))
+}
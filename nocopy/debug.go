@@ -0,0 +1,157 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"weak"
+)
+
+// ErrMutated is reported by Verify when a region lent out during decoding has changed.
+var ErrMutated = errors.New("nocopy: borrowed buffer changed after decode")
+
+var (
+	debugging  atomic.Bool
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+	loans      struct {
+		sync.Mutex
+		owners map[uintptr]*borrower // keyed by the address of the decoded value
+	}
+)
+
+// borrower is a decoded value along with the regions it points into.
+type borrower struct {
+	ref     weak.Pointer[byte] // the value
+	typ     reflect.Type
+	regions []loan
+}
+
+type loan struct {
+	data []byte
+	sum  uint32
+}
+
+// alive reports whether the value holding the regions can still be used.
+func (b *borrower) alive() bool {
+	return b.ref.Value() != nil
+}
+
+// Debug enables or disables tracking of every region lent out by the decoders of this
+// package. While enabled, each decoded region is checksummed so that Verify can detect
+// the input buffer being modified or recycled while the decoded values are still in
+// use. Values that are not addressable are not tracked. Tracking keeps the regions
+// reachable and is meant for tests and debugging only. Toggling the switch discards the regions recorded so far.
+func Debug(enabled bool) {
+	loans.Lock()
+	loans.owners = nil
+	debugging.Store(enabled)
+	loans.Unlock()
+}
+
+// Verify checks the regions lent to the values still in use and returns an error
+// wrapping ErrMutated for each one whose contents changed after decode. A value stops
+// being tracked once it is garbage collected, decoded again or passed to Release, so
+// its buffer can then be recycled. Errors name the type of the value, not its field.
+func Verify() error {
+	loans.Lock()
+	defer loans.Unlock()
+
+	var errs []error
+	for addr, owner := range loans.owners {
+		if !owner.alive() {
+			delete(loans.owners, addr)
+			continue
+		}
+
+		for _, region := range owner.regions {
+			if crc32.Checksum(region.data, castagnoli) != region.sum {
+				errs = append(errs, fmt.Errorf("%w: %s (%d bytes at %p)",
+					ErrMutated, owner.typ, len(region.data), &region.data[0]))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Release stops tracking the regions lent to the no-copy values reachable from v, such
+// as a decode target about to be reset, before their buffer is recycled.
+func Release(v any) {
+	if !debugging.Load() || v == nil {
+		return
+	}
+
+	loans.Lock()
+	defer loans.Unlock()
+	release(reflect.ValueOf(v))
+}
+
+func release(rv reflect.Value) {
+	t := rv.Type()
+	if _, ok := aliasing[t]; ok {
+		if rv.CanAddr() {
+			delete(loans.owners, rv.UnsafeAddr())
+		}
+		return
+	}
+	if !mayAlias(t) {
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !rv.IsNil() {
+			release(rv.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			release(rv.Index(i))
+		}
+	case reflect.Struct:
+		for _, i := range encodedFields(t) {
+			release(rv.Field(i))
+		}
+	}
+}
+
+// reclaim stops tracking the regions of a value which is about to be decoded again.
+func reclaim(rv reflect.Value) {
+	if !debugging.Load() || !rv.CanAddr() {
+		return
+	}
+
+	loans.Lock()
+	delete(loans.owners, rv.UnsafeAddr())
+	loans.Unlock()
+}
+
+// lend records a region the value points into when debugging is enabled. Values that
+// are not addressable have no lifetime to tie the region to, and are not tracked.
+func lend(rv reflect.Value, b []byte) {
+	if !debugging.Load() || len(b) == 0 || !rv.CanAddr() {
+		return
+	}
+
+	sum := crc32.Checksum(b, castagnoli)
+	loans.Lock()
+	defer loans.Unlock()
+	if !debugging.Load() {
+		return
+	}
+
+	addr := rv.UnsafeAddr()
+	owner := loans.owners[addr]
+	if owner == nil || !owner.alive() || owner.typ != rv.Type() {
+		owner = &borrower{typ: rv.Type(), ref: weak.Make((*byte)(rv.Addr().UnsafePointer()))}
+		if loans.owners == nil {
+			loans.owners = make(map[uintptr]*borrower)
+		}
+		loans.owners[addr] = owner
+	}
+	owner.regions = append(owner.regions, loan{data: b, sum: sum})
+}
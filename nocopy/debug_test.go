@@ -0,0 +1,92 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/kelindar/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug(t *testing.T) {
+	Debug(true)
+	defer Debug(false)
+
+	in := detachMessage{
+		Name:    "Roman",
+		Payload: Bytes("payload"),
+		Values:  Uint32s{1, 2, 3},
+		Tags:    Dictionary{"key": "value"},
+	}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	var out detachMessage
+	assert.NoError(t, binary.Unmarshal(encoded, &out))
+	assert.NoError(t, Verify())
+
+	// Recycle the buffer while the decoded value is still alive
+	clear(encoded)
+	err = Verify()
+	assert.True(t, errors.Is(err, ErrMutated))
+	assert.Contains(t, err.Error(), "nocopy.String")
+	assert.Contains(t, err.Error(), "nocopy.Bytes")
+
+	// Turning debugging off drops the recorded regions
+	Debug(false)
+	assert.NoError(t, Verify())
+	assert.NoError(t, binary.Unmarshal(encoded, &out))
+	assert.NoError(t, Verify())
+}
+
+func TestDebugLifetime(t *testing.T) {
+	Debug(true)
+	defer Debug(false)
+
+	in := detachMessage{
+		Name:    "Roman",
+		Payload: Bytes("payload"),
+		Tags:    Dictionary{"key": "value"},
+		Nested:  []*nested{{Numbers: Uint64s{4, 5}}},
+	}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	// A decode target reused for the next message stops borrowing the previous buffer
+	out := new(detachMessage)
+	assert.NoError(t, binary.Unmarshal(encoded, out))
+	next := append([]byte(nil), encoded...)
+	assert.NoError(t, binary.Unmarshal(next, out))
+	clear(encoded)
+	assert.NoError(t, Verify())
+
+	// Released values are no longer checked
+	Release(out)
+	clear(next)
+	assert.NoError(t, Verify())
+
+	// Nor are the values that were dropped
+	encoded, err = binary.Marshal(&in)
+	assert.NoError(t, err)
+	assert.NoError(t, binary.Unmarshal(encoded, new(detachMessage)))
+	runtime.GC()
+	clear(encoded)
+	assert.NoError(t, Verify())
+}
+
+func TestDebugUnaddressable(t *testing.T) {
+	Debug(true)
+	defer Debug(false)
+
+	// Values without an address of their own cannot be told apart, so they are not tracked
+	lend(reflect.ValueOf(Bytes("first")), []byte("first"))
+	lend(reflect.ValueOf(String("second")), []byte("second"))
+	loans.Lock()
+	assert.Empty(t, loans.owners)
+	loans.Unlock()
+}
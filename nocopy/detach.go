@@ -0,0 +1,243 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/kelindar/binary/internal/fields"
+)
+
+var errNotPointer = errors.New("nocopy: can only detach a non-nil pointer")
+
+// aliasing lists the types whose decoded values point into the input buffer.
+var aliasing = map[reflect.Type]struct{}{
+	reflect.TypeFor[String]():     {},
+	reflect.TypeFor[Bytes]():      {},
+	reflect.TypeFor[JSON]():       {},
+	reflect.TypeFor[Bools]():      {},
+	reflect.TypeFor[Uint16s]():    {},
+	reflect.TypeFor[Int16s]():     {},
+	reflect.TypeFor[Uint32s]():    {},
+	reflect.TypeFor[Int32s]():     {},
+	reflect.TypeFor[Uint64s]():    {},
+	reflect.TypeFor[Int64s]():     {},
+	reflect.TypeFor[Float32s]():   {},
+	reflect.TypeFor[Float64s]():   {},
+	reflect.TypeFor[Dictionary](): {},
+	reflect.TypeFor[ByteMap]():    {},
+	reflect.TypeFor[HashMap]():    {},
+}
+
+// Detach walks the value pointed to by v and copies every buffer held by a no-copy
+// type into owned memory, so that the value stays valid after the input it was
+// decoded from is reused. Struct fields are walked only if their codec decodes them,
+// which covers the arms of OneOf and Versioned values, except in types with their own
+// codec, whose exported fields are walked.
+func Detach(v any) error {
+	return detach(v, detacher{})
+}
+
+// DetachFrom is like Detach, but only copies strings and slices whose memory points
+// into src, regardless of their type.
+func DetachFrom(src []byte, v any) error {
+	if len(src) == 0 {
+		return detach(v, detacher{ranged: true})
+	}
+	lo := uintptr(unsafe.Pointer(unsafe.SliceData(src)))
+	return detach(v, detacher{ranged: true, lo: lo, hi: lo + uintptr(len(src))})
+}
+
+func detach(v any, w detacher) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errNotPointer
+	}
+	w.walk(rv.Elem(), false)
+	return nil
+}
+
+type detacher struct {
+	ranged bool    // only copy memory in [lo, hi)
+	lo, hi uintptr // source range
+}
+
+func (w *detacher) match(p unsafe.Pointer, size int, aliased bool) bool {
+	switch {
+	case p == nil || size == 0:
+		return false
+	case !w.ranged:
+		return aliased
+	default:
+		addr := uintptr(p)
+		return addr >= w.lo && addr < w.hi
+	}
+}
+
+func (w *detacher) walk(rv reflect.Value, aliased bool) {
+	t := rv.Type()
+	if _, ok := aliasing[t]; ok {
+		aliased = true
+	}
+	if !mayAlias(t) {
+		return
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		s := rv.String()
+		if w.match(unsafe.Pointer(unsafe.StringData(s)), len(s), aliased) && rv.CanSet() {
+			rv.SetString(strings.Clone(s))
+		}
+	case reflect.Slice:
+		if rv.IsNil() {
+			return
+		}
+		if isPlain(t.Elem()) {
+			size := rv.Len() * int(t.Elem().Size())
+			if w.match(rv.UnsafePointer(), size, aliased) && rv.CanSet() {
+				clone := reflect.MakeSlice(t, rv.Len(), rv.Len())
+				reflect.Copy(clone, rv)
+				rv.Set(clone)
+			}
+			return
+		}
+		for i := range rv.Len() {
+			w.walk(rv.Index(i), aliased)
+		}
+	case reflect.Array:
+		for i := range rv.Len() {
+			w.walk(rv.Index(i), aliased)
+		}
+	case reflect.Pointer:
+		if !rv.IsNil() {
+			w.walk(rv.Elem(), aliased)
+		}
+	case reflect.Interface:
+		if rv.IsNil() || !rv.CanSet() {
+			return
+		}
+		elem := reflect.New(rv.Elem().Type()).Elem()
+		elem.Set(rv.Elem())
+		w.walk(elem, aliased)
+		rv.Set(elem)
+	case reflect.Struct:
+		for _, i := range encodedFields(t) {
+			if field := fieldOf(rv, i); field.CanSet() {
+				w.walk(field, aliased)
+			}
+		}
+	case reflect.Map:
+		if rv.IsNil() || !rv.CanSet() {
+			return
+		}
+
+		// Assigning an equal key replaces the stored key, so each entry can be
+		// detached and written back in place.
+		key := reflect.New(t.Key()).Elem()
+		value := reflect.New(t.Elem()).Elem()
+		keys := rv.MapKeys()
+		for _, k := range keys {
+			key.Set(k)
+			value.Set(rv.MapIndex(k))
+			w.walk(key, aliased)
+			w.walk(value, aliased)
+			rv.SetMapIndex(key, value)
+		}
+	}
+}
+
+var fieldCache sync.Map // reflect.Type -> []int
+
+// encodedFields returns the indices of the fields of a struct decoded by its codec, some
+// of which may be unexported, or of its exported fields when the codec does not decode it
+// field by field.
+func encodedFields(t reflect.Type) []int {
+	if v, ok := fieldCache.Load(t); ok {
+		return v.([]int)
+	}
+	indices, err := fields.Encoded(t)
+	if err != nil || indices == nil {
+		indices = make([]int, 0, t.NumField())
+		for i := range t.NumField() {
+			if t.Field(i).IsExported() {
+				indices = append(indices, i)
+			}
+		}
+	}
+	fieldCache.Store(t, indices)
+	return indices
+}
+
+// fieldOf returns the field of rv at index i, settable even if it is unexported as long
+// as rv is addressable, since the codec listed it.
+func fieldOf(rv reflect.Value, i int) reflect.Value {
+	field := rv.Field(i)
+	if !field.CanSet() && field.CanAddr() {
+		field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+	}
+	return field
+}
+
+var aliasCache sync.Map // reflect.Type -> bool
+
+// mayAlias reports whether a value of the type can reference memory it does not own.
+func mayAlias(t reflect.Type) bool {
+	if v, ok := aliasCache.Load(t); ok {
+		return v.(bool)
+	}
+	v := scanAlias(t, make(map[reflect.Type]bool))
+	aliasCache.Store(t, v)
+	return v
+}
+
+func scanAlias(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if v, ok := seen[t]; ok {
+		return v
+	}
+	seen[t] = true // recursive types may alias through themselves
+	var out bool
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Interface:
+		out = true
+	case reflect.Pointer:
+		out = scanAlias(t.Elem(), seen)
+	case reflect.Array:
+		out = t.Len() > 0 && scanAlias(t.Elem(), seen)
+	case reflect.Struct:
+		for _, i := range encodedFields(t) {
+			if scanAlias(t.Field(i).Type, seen) {
+				out = true
+				break
+			}
+		}
+	}
+	seen[t] = out
+	return out
+}
+
+// isPlain reports whether the type holds no pointers, so a slice of it can be copied as memory.
+func isPlain(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isPlain(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if !isPlain(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
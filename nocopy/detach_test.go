@@ -0,0 +1,126 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/kelindar/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+type detachMessage struct {
+	Name    String
+	Payload Bytes
+	Values  Uint32s
+	Flags   Bools
+	Tags    Dictionary
+	Blobs   ByteMap
+	Nested  []*nested
+	Plain   string
+	Any     any `binary:"-"`
+}
+
+func TestDetach(t *testing.T) {
+	in := detachMessage{
+		Name:    "Roman",
+		Payload: Bytes("payload"),
+		Values:  Uint32s{1, 2, 3},
+		Flags:   Bools{true, false},
+		Tags:    Dictionary{"key": "value"},
+		Blobs:   ByteMap{"blob": []byte("data")},
+		Nested:  []*nested{{Numbers: Uint64s{4, 5}}, nil},
+		Plain:   "plain",
+	}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	var out detachMessage
+	assert.NoError(t, binary.Unmarshal(encoded, &out))
+	assert.NoError(t, Detach(&out))
+	clear(encoded)
+	assert.Equal(t, in, out)
+}
+
+func TestDetachFrom(t *testing.T) {
+	in := detachMessage{Name: "Roman", Payload: Bytes("payload"), Values: Uint32s{1, 2, 3}}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	var out detachMessage
+	assert.NoError(t, binary.Unmarshal(encoded, &out))
+	out.Any = String("owned")
+	owned := out.Any
+	assert.NoError(t, DetachFrom(encoded, &out))
+	clear(encoded)
+	assert.Equal(t, in.Name, out.Name)
+	assert.Equal(t, in.Payload, out.Payload)
+	assert.Equal(t, in.Values, out.Values)
+	assert.Equal(t, owned, out.Any)
+
+	// Memory outside of the source range is left untouched
+	other := Bytes("other")
+	value := struct{ Data Bytes }{Data: other}
+	assert.NoError(t, DetachFrom(encoded, &value))
+	assert.True(t, &other[0] == &value.Data[0])
+}
+
+func TestDetachErrors(t *testing.T) {
+	var out detachMessage
+	assert.Error(t, Detach(out))
+	assert.Error(t, Detach((*detachMessage)(nil)))
+	assert.Error(t, DetachFrom(nil, nil))
+}
+
+func TestDetachSkipsUnencoded(t *testing.T) {
+	src := []byte("skipped")
+	value := struct {
+		Name    String
+		Skipped Bytes `binary:"-"`
+	}{Name: String(src[:4]), Skipped: Bytes(src)}
+
+	assert.NoError(t, DetachFrom(src, &value))
+	assert.False(t, unsafe.StringData(string(value.Name)) == &src[0])
+	assert.True(t, &value.Skipped[0] == &src[0])
+}
+
+type detachArm struct {
+	Data Bytes
+}
+
+type detachUnions struct {
+	Arm       binary.OneOf2[detachArm, nested]
+	Versioned binary.Versioned[detachArm]
+}
+
+func TestDetachUnions(t *testing.T) {
+	var in detachUnions
+	in.Arm.SetA(&detachArm{Data: Bytes("arm")})
+	in.Versioned.Value = &detachArm{Data: Bytes("versioned")}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	// The arms of OneOf and Versioned values are detached too
+	var out detachUnions
+	assert.NoError(t, binary.Unmarshal(encoded, &out))
+	assert.NoError(t, Detach(&out))
+	clear(encoded)
+	arm, ok := out.Arm.A()
+	assert.True(t, ok)
+	assert.Equal(t, Bytes("arm"), arm.Data)
+	assert.Equal(t, Bytes("versioned"), out.Versioned.Value.Data)
+
+	var top binary.OneOf2[detachArm, nested]
+	top.SetA(&detachArm{Data: Bytes("top")})
+	encoded, err = binary.Marshal(&top)
+	assert.NoError(t, err)
+
+	var outTop binary.OneOf2[detachArm, nested]
+	assert.NoError(t, binary.Unmarshal(encoded, &outTop))
+	assert.NoError(t, DetachFrom(encoded, &outTop))
+	clear(encoded)
+	arm, _ = outTop.A()
+	assert.Equal(t, Bytes("top"), arm.Data)
+}
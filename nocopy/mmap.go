@@ -0,0 +1,117 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kelindar/binary"
+)
+
+var (
+	errReleased = errors.New("nocopy: mapping released too many times")
+	errUnmapped = errors.New("nocopy: mapping already unmapped")
+)
+
+// Mapping is a read-only view of a file containing binary-encoded data. Values of the
+// no-copy types decoded from it alias the mapped memory directly, so the file can be
+// queried without being loaded first. Such values must not be written to, and must not
+// be used after the last reference to the mapping has been released.
+type Mapping struct {
+	lock     sync.RWMutex // held for reading while the memory is in use
+	data     []byte
+	unmapped bool
+	refs     atomic.Int64
+	closed   atomic.Bool
+}
+
+// MapFile maps the file at the given path into memory. The returned mapping holds a
+// single reference, which is dropped by Close.
+func MapFile(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mapping{data: data}
+	m.refs.Store(1)
+	return m, nil
+}
+
+// Bytes returns the mapped memory, which is only valid while a reference is held, or an
+// error once the mapping has been unmapped.
+func (m *Mapping) Bytes() ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.unmapped {
+		return nil, errUnmapped
+	}
+	return m.data, nil
+}
+
+// Len returns the size of the mapping in bytes, or zero once it has been unmapped.
+func (m *Mapping) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.data)
+}
+
+// Unmarshal decodes the mapped contents into v, or returns an error once the mapping
+// has been unmapped. The memory is not unmapped while the value is being decoded.
+func (m *Mapping) Unmarshal(v any) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.unmapped {
+		return errUnmapped
+	}
+	return binary.Unmarshal(m.data, v)
+}
+
+// Acquire takes an additional reference on the mapping, returning false if the mapping
+// has already been unmapped.
+func (m *Mapping) Acquire() bool {
+	for {
+		n := m.refs.Load()
+		if n <= 0 {
+			return false
+		}
+		if m.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// Release drops a reference on the mapping, unmapping the memory once the last
+// reference is gone.
+func (m *Mapping) Release() error {
+	switch n := m.refs.Add(-1); {
+	case n > 0:
+		return nil
+	case n < 0:
+		return errReleased
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data := m.data
+	m.data, m.unmapped = nil, true
+	return unmapFile(data)
+}
+
+// Close drops the reference taken by MapFile. It is safe to call more than once.
+func (m *Mapping) Close() error {
+	if m.closed.Swap(true) {
+		return nil
+	}
+	return m.Release()
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the whole file on platforms without a memory-mapped implementation.
+func mapFile(f *os.File) ([]byte, error) {
+	return io.ReadAll(f)
+}
+
+func unmapFile([]byte) error {
+	return nil
+}
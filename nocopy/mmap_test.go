@@ -0,0 +1,96 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package nocopy
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/kelindar/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapFile(t *testing.T) {
+	in := columnFloat64{
+		Nulls:  Bools{false, true, false},
+		Floats: Float64s{1.5, 2.5, 3.5},
+	}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "table.bin")
+	assert.NoError(t, os.WriteFile(path, encoded, 0o644))
+
+	m, err := MapFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(encoded), m.Len())
+
+	var out columnFloat64
+	assert.NoError(t, m.Unmarshal(&out))
+	assert.Equal(t, in, out)
+
+	// Decoded values alias the mapped memory
+	data, err := m.Bytes()
+	assert.NoError(t, err)
+	start := uintptr(unsafe.Pointer(&data[0]))
+	addr := uintptr(unsafe.Pointer(&out.Floats[0]))
+	assert.True(t, addr >= start && addr < start+uintptr(m.Len()))
+
+	// The mapping stays alive while a reference is held
+	assert.True(t, m.Acquire())
+	assert.NoError(t, m.Close())
+	assert.NoError(t, m.Close())
+	assert.Equal(t, in, out)
+	assert.NoError(t, m.Release())
+	assert.False(t, m.Acquire())
+
+	// The memory can no longer be reached once unmapped
+	_, err = m.Bytes()
+	assert.Error(t, err)
+	assert.Error(t, m.Unmarshal(&out))
+	assert.Equal(t, 0, m.Len())
+	assert.Error(t, m.Release())
+}
+
+func TestMapFileConcurrentRelease(t *testing.T) {
+	in := columnFloat64{Floats: Float64s{1.5, 2.5}}
+	encoded, err := binary.Marshal(&in)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "table.bin")
+	assert.NoError(t, os.WriteFile(path, encoded, 0o644))
+	m, err := MapFile(path)
+	assert.NoError(t, err)
+
+	// Decoding either sees the mapping or fails, while it is unmapped concurrently
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out columnFloat64
+			if err := m.Unmarshal(&out); err == nil {
+				assert.Equal(t, len(in.Floats), len(out.Floats))
+			}
+			m.Bytes()
+		}()
+	}
+	assert.NoError(t, m.Close())
+	wg.Wait()
+}
+
+func TestMapFileErrors(t *testing.T) {
+	_, err := MapFile(filepath.Join(t.TempDir(), "missing.bin"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "empty.bin")
+	assert.NoError(t, os.WriteFile(path, nil, 0o644))
+	m, err := MapFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, m.Len())
+	assert.NoError(t, m.Close())
+}
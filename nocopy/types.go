@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/kelindar/binary"
+	"github.com/kelindar/binary/internal/byteorder"
 	"io"
 	"reflect"
 	"unsafe"
@@ -151,16 +152,18 @@ func integerCodec[T any](size int) binary.Codec {
 }
 func (c *integerSliceCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err error) {
 	n := rv.Len() * c.sizeOfInt
-	e.WriteUint64(uint64(n))
+	e.WriteUint64(uint64(n) | byteorder.Native)
 	e.Write(unsafe.Slice((*byte)(rv.UnsafePointer()), n))
 	return
 }
 func (c *integerSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var l uint64
 	if l, err = d.ReadUint64(); err != nil {
 		return
 	}
-	if l == 0 {
+	order := l & byteorder.Flag
+	if l &^= byteorder.Flag; l == 0 {
 		rv.SetZero()
 		return nil
 	}
@@ -172,9 +175,21 @@ func (c *integerSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err e
 		return io.ErrUnexpectedEOF
 	}
 	var b []byte
-	if b, err = d.Slice(n); err == nil {
-		setSlice(rv, unsafe.Pointer(unsafe.SliceData(b)), n/c.sizeOfInt)
+	if b, err = d.Slice(n); err != nil {
+		return
+	}
+	if order != byteorder.Native {
+		// Payload written by a host with a different byte order cannot be
+		// borrowed, so fall back to a swapped copy.
+		src := reflect.MakeSlice(rv.Type(), n/c.sizeOfInt, n/c.sizeOfInt)
+		data := unsafe.Slice((*byte)(src.UnsafePointer()), n)
+		copy(data, b)
+		byteorder.Swap(data, c.sizeOfInt)
+		rv.Set(src)
+		return nil
 	}
+	lend(rv, b)
+	setSlice(rv, unsafe.Pointer(unsafe.SliceData(b)), n/c.sizeOfInt)
 	return
 }
 
@@ -197,6 +212,7 @@ func (c *byteSliceCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err erro
 	return
 }
 func (c *byteSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var b []byte
 	if b, err = d.ReadSlice(); err != nil {
 		return
@@ -205,6 +221,7 @@ func (c *byteSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err erro
 		rv.SetZero()
 		return nil
 	}
+	lend(rv, b)
 	setSlice(rv, unsafe.Pointer(unsafe.SliceData(b)), len(b))
 	return
 }
@@ -220,8 +237,10 @@ func (c *stringCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) error {
 	return nil
 }
 func (c *stringCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var v []byte
 	if v, err = d.ReadSlice(); err == nil {
+		lend(rv, v)
 		*(*string)(unsafe.Pointer(rv.UnsafeAddr())) = binary.ToString(&v)
 	}
 	return
@@ -241,6 +260,7 @@ func (c *boolSliceCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err erro
 	return
 }
 func (c *boolSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var l uint64
 	var v []byte
 	if l, err = d.ReadUvarint(); err != nil {
@@ -255,6 +275,7 @@ func (c *boolSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err erro
 		return err
 	}
 	if v, err = d.Slice(n); err == nil {
+		lend(rv, v)
 		b := binaryToBools(&v)
 		setSlice(rv, unsafe.Pointer(unsafe.SliceData(b)), len(b))
 	}
@@ -298,6 +319,7 @@ func (c *byteMapCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err error)
 	return
 }
 func (c *byteMapCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var size uint16
 	if size, err = d.ReadUint16(); err == nil {
 		n := int(size)
@@ -313,7 +335,7 @@ func (c *byteMapCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error)
 			clear(dict)
 		}
 		for i := 0; i < n; i++ {
-			k, err := decodeString(d)
+			k, err := decodeString(d, rv)
 			if err != nil {
 				return err
 			}
@@ -330,6 +352,7 @@ func (c *byteMapCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error)
 				if b, err = d.Slice(n); err != nil {
 					return err
 				}
+				lend(rv, b)
 				b = b[:len(b):len(b)]
 			}
 			dict[k] = b
@@ -379,6 +402,7 @@ func (c *hashMapCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err error)
 	return
 }
 func (c *hashMapCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var size uint32
 	if size, err = d.ReadUint32(); err == nil {
 		n, err := decodeLength(uint64(size))
@@ -414,6 +438,7 @@ func (c *hashMapCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error)
 				if b, err = d.Slice(n); err != nil {
 					return err
 				}
+				lend(rv, b)
 				b = b[:len(b):len(b)]
 			}
 			dict[k] = b
@@ -451,6 +476,7 @@ func (c *dictionaryCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err err
 	return
 }
 func (c *dictionaryCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	reclaim(rv)
 	var size uint16
 	if size, err = d.ReadUint16(); err == nil {
 		capacity, err := mapCapacity(d, int(size), 2)
@@ -465,11 +491,11 @@ func (c *dictionaryCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err err
 			clear(dict)
 		}
 		for i := 0; i < int(size); i++ {
-			k, err := decodeString(d)
+			k, err := decodeString(d, rv)
 			if err != nil {
 				return err
 			}
-			v, err := decodeString(d)
+			v, err := decodeString(d, rv)
 			if err != nil {
 				return err
 			}
@@ -486,9 +512,10 @@ func uvarintSize(x uint64) int {
 	}
 	return size
 }
-func decodeString(d *binary.Decoder) (v string, err error) {
+func decodeString(d *binary.Decoder, owner reflect.Value) (v string, err error) {
 	var b []byte
 	if b, err = d.ReadSlice(); err == nil {
+		lend(owner, b)
 		v = binary.ToString(&b)
 	}
 	return
@@ -4,6 +4,7 @@
 package nocopy
 
 import (
+	"bufio"
 	"bytes"
 	stdbinary "encoding/binary"
 	"encoding/json"
@@ -14,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/kelindar/binary"
+	"github.com/kelindar/binary/internal/byteorder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -203,6 +205,42 @@ func TestTypes(t *testing.T) {
 	})
 }
 
+func TestCopySlicesBufio(t *testing.T) {
+	var input bytes.Buffer
+	e := binary.NewEncoder(&input)
+	for _, v := range []string{"aaaaaaaaa", "bbbbbbbbb", "ccccccccc", "ddddddddd"} {
+		assert.NoError(t, e.Encode(Bytes(v)))
+	}
+
+	// Values decoded earlier keep their bytes while the small buffer is refilled
+	d := binary.NewDecoder(bufio.NewReaderSize(&input, 16))
+	d.CopySlices()
+	out := make([]Bytes, 4)
+	for i := range out {
+		assert.NoError(t, d.Decode(&out[i]))
+	}
+	assert.Equal(t, []Bytes{
+		Bytes("aaaaaaaaa"), Bytes("bbbbbbbbb"), Bytes("ccccccccc"), Bytes("ddddddddd"),
+	}, out)
+}
+
+func TestByteOrder(t *testing.T) {
+	encoded, err := binary.Marshal(Uint16s{1, 0x0102})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), stdbinary.LittleEndian.Uint64(encoded[:8]))
+
+	// Simulate a payload written by a big-endian host, which must be copied
+	foreign := stdbinary.LittleEndian.AppendUint64(nil, 4|byteorder.Flag)
+	foreign = stdbinary.BigEndian.AppendUint16(foreign, 1)
+	foreign = stdbinary.BigEndian.AppendUint16(foreign, 0x0102)
+
+	var out Uint16s
+	assert.NoError(t, binary.Unmarshal(foreign, &out))
+	assert.Equal(t, Uint16s{1, 0x0102}, out)
+	clear(foreign)
+	assert.Equal(t, Uint16s{1, 0x0102}, out)
+}
+
 func TestSort(t *testing.T) {
 	tests := map[string]struct {
 		value sort.Interface
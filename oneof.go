@@ -0,0 +1,260 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// oneOf is the state shared by the OneOf types: the position of the set arm, starting
+// at 1 (0 = none), and a pointer to its value. On every OneOf type, the getter of an arm
+// returns its value and whether it is set, and its setter replaces any other arm, with
+// nil clearing the OneOf.
+type oneOf struct {
+	tag   int
+	value any
+}
+
+// Which returns the position of the arm that is set, starting at 1, or 0 if none is.
+func (o oneOf) Which() int {
+	return o.tag
+}
+
+// Reset clears the arm that is set.
+func (o *oneOf) Reset() {
+	*o = oneOf{}
+}
+
+func (o *oneOf) set(tag int, value any, isNil bool) {
+	if isNil {
+		*o = oneOf{}
+		return
+	}
+	*o = oneOf{tag: tag, value: value}
+}
+
+func getArm[T any](o oneOf, tag int) (*T, bool) {
+	if o.tag != tag {
+		return nil, false
+	}
+	return o.value.(*T), true
+}
+
+func scanOneOf(types ...reflect.Type) (Codec, error) {
+	c := &oneOfCodec{types: types, codecs: make([]Codec, len(types))}
+	for i, typ := range types {
+		codec, err := scanType(typ)
+		if err != nil {
+			return nil, err
+		}
+		c.codecs[i] = codec
+	}
+	return c, nil
+}
+
+// oneOfCodec encodes a OneOf type with the same framing as a tagged union struct.
+type oneOfCodec struct {
+	types  []reflect.Type
+	codecs []Codec
+}
+
+func (c *oneOfCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	o := oneOfOf(rv)
+	if o.tag == 0 {
+		e.WriteTagged(0, nil)
+		return e.err
+	}
+	e.WriteUvarint(uint64(o.tag))
+	return e.writeFramed(c.codecs[o.tag-1], reflect.ValueOf(o.value).Elem())
+}
+
+func (c *oneOfCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	tag, body, err := d.ReadTagged()
+	if err != nil {
+		return err
+	}
+	o := (*oneOf)(unsafe.Pointer(rv.UnsafeAddr()))
+	if tag == 0 || tag > uint64(len(c.types)) {
+		*o = oneOf{} // unknown arms are skipped
+		return nil
+	}
+
+	// Reuse the value of the arm when it is already set, as tagged unions do
+	var ptr reflect.Value
+	if o.tag == int(tag) {
+		ptr = reflect.ValueOf(o.value)
+	} else {
+		ptr = reflect.New(c.types[tag-1])
+	}
+	*o = oneOf{}
+	if err := decodeArm(d, c.codecs[tag-1], body, ptr.Elem()); err != nil {
+		return err
+	}
+	*o = oneOf{tag: int(tag), value: ptr.Interface()}
+	return nil
+}
+
+func oneOfOf(rv reflect.Value) oneOf {
+	if rv.CanAddr() {
+		return *(*oneOf)(unsafe.Pointer(rv.UnsafeAddr()))
+	}
+	tmp := reflect.New(rv.Type()).Elem()
+	tmp.Set(rv)
+	return *(*oneOf)(unsafe.Pointer(tmp.UnsafeAddr()))
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf2 holds at most one value out of 2 types, tagged by position starting at 1.
+type OneOf2[A, B any] struct {
+	oneOf
+}
+
+func (o OneOf2[A, B]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf2[A, B]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf2[A, B]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf2[A, B]) SetB(v *B)    { o.set(2, v, v == nil) }
+
+func (*OneOf2[A, B]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf3 holds at most one value out of 3 types, tagged by position starting at 1.
+type OneOf3[A, B, C any] struct {
+	oneOf
+}
+
+func (o OneOf3[A, B, C]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf3[A, B, C]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf3[A, B, C]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf3[A, B, C]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf3[A, B, C]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf3[A, B, C]) SetC(v *C)    { o.set(3, v, v == nil) }
+
+func (*OneOf3[A, B, C]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf4 holds at most one value out of 4 types, tagged by position starting at 1.
+type OneOf4[A, B, C, D any] struct {
+	oneOf
+}
+
+func (o OneOf4[A, B, C, D]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf4[A, B, C, D]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf4[A, B, C, D]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf4[A, B, C, D]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf4[A, B, C, D]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf4[A, B, C, D]) SetC(v *C)    { o.set(3, v, v == nil) }
+func (o OneOf4[A, B, C, D]) D() (*D, bool) { return getArm[D](o.oneOf, 4) }
+func (o *OneOf4[A, B, C, D]) SetD(v *D)    { o.set(4, v, v == nil) }
+
+func (*OneOf4[A, B, C, D]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C](), reflect.TypeFor[D]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf5 holds at most one value out of 5 types, tagged by position starting at 1.
+type OneOf5[A, B, C, D, E any] struct {
+	oneOf
+}
+
+func (o OneOf5[A, B, C, D, E]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf5[A, B, C, D, E]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf5[A, B, C, D, E]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf5[A, B, C, D, E]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf5[A, B, C, D, E]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf5[A, B, C, D, E]) SetC(v *C)    { o.set(3, v, v == nil) }
+func (o OneOf5[A, B, C, D, E]) D() (*D, bool) { return getArm[D](o.oneOf, 4) }
+func (o *OneOf5[A, B, C, D, E]) SetD(v *D)    { o.set(4, v, v == nil) }
+func (o OneOf5[A, B, C, D, E]) E() (*E, bool) { return getArm[E](o.oneOf, 5) }
+func (o *OneOf5[A, B, C, D, E]) SetE(v *E)    { o.set(5, v, v == nil) }
+
+func (*OneOf5[A, B, C, D, E]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C](), reflect.TypeFor[D](), reflect.TypeFor[E]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf6 holds at most one value out of 6 types, tagged by position starting at 1.
+type OneOf6[A, B, C, D, E, F any] struct {
+	oneOf
+}
+
+func (o OneOf6[A, B, C, D, E, F]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf6[A, B, C, D, E, F]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf6[A, B, C, D, E, F]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf6[A, B, C, D, E, F]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf6[A, B, C, D, E, F]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf6[A, B, C, D, E, F]) SetC(v *C)    { o.set(3, v, v == nil) }
+func (o OneOf6[A, B, C, D, E, F]) D() (*D, bool) { return getArm[D](o.oneOf, 4) }
+func (o *OneOf6[A, B, C, D, E, F]) SetD(v *D)    { o.set(4, v, v == nil) }
+func (o OneOf6[A, B, C, D, E, F]) E() (*E, bool) { return getArm[E](o.oneOf, 5) }
+func (o *OneOf6[A, B, C, D, E, F]) SetE(v *E)    { o.set(5, v, v == nil) }
+func (o OneOf6[A, B, C, D, E, F]) F() (*F, bool) { return getArm[F](o.oneOf, 6) }
+func (o *OneOf6[A, B, C, D, E, F]) SetF(v *F)    { o.set(6, v, v == nil) }
+
+func (*OneOf6[A, B, C, D, E, F]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C](), reflect.TypeFor[D](), reflect.TypeFor[E](), reflect.TypeFor[F]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf7 holds at most one value out of 7 types, tagged by position starting at 1.
+type OneOf7[A, B, C, D, E, F, G any] struct {
+	oneOf
+}
+
+func (o OneOf7[A, B, C, D, E, F, G]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetC(v *C)    { o.set(3, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) D() (*D, bool) { return getArm[D](o.oneOf, 4) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetD(v *D)    { o.set(4, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) E() (*E, bool) { return getArm[E](o.oneOf, 5) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetE(v *E)    { o.set(5, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) F() (*F, bool) { return getArm[F](o.oneOf, 6) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetF(v *F)    { o.set(6, v, v == nil) }
+func (o OneOf7[A, B, C, D, E, F, G]) G() (*G, bool) { return getArm[G](o.oneOf, 7) }
+func (o *OneOf7[A, B, C, D, E, F, G]) SetG(v *G)    { o.set(7, v, v == nil) }
+
+func (*OneOf7[A, B, C, D, E, F, G]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C](), reflect.TypeFor[D](), reflect.TypeFor[E](), reflect.TypeFor[F](), reflect.TypeFor[G]())
+}
+
+// ------------------------------------------------------------------------------
+
+// OneOf8 holds at most one value out of 8 types, tagged by position starting at 1.
+type OneOf8[A, B, C, D, E, F, G, H any] struct {
+	oneOf
+}
+
+func (o OneOf8[A, B, C, D, E, F, G, H]) A() (*A, bool) { return getArm[A](o.oneOf, 1) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetA(v *A)    { o.set(1, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) B() (*B, bool) { return getArm[B](o.oneOf, 2) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetB(v *B)    { o.set(2, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) C() (*C, bool) { return getArm[C](o.oneOf, 3) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetC(v *C)    { o.set(3, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) D() (*D, bool) { return getArm[D](o.oneOf, 4) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetD(v *D)    { o.set(4, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) E() (*E, bool) { return getArm[E](o.oneOf, 5) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetE(v *E)    { o.set(5, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) F() (*F, bool) { return getArm[F](o.oneOf, 6) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetF(v *F)    { o.set(6, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) G() (*G, bool) { return getArm[G](o.oneOf, 7) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetG(v *G)    { o.set(7, v, v == nil) }
+func (o OneOf8[A, B, C, D, E, F, G, H]) H() (*H, bool) { return getArm[H](o.oneOf, 8) }
+func (o *OneOf8[A, B, C, D, E, F, G, H]) SetH(v *H)    { o.set(8, v, v == nil) }
+
+func (*OneOf8[A, B, C, D, E, F, G, H]) scanCodec() (Codec, error) {
+	return scanOneOf(reflect.TypeFor[A](), reflect.TypeFor[B](), reflect.TypeFor[C](), reflect.TypeFor[D](), reflect.TypeFor[E](), reflect.TypeFor[F](), reflect.TypeFor[G](), reflect.TypeFor[H]())
+}
@@ -0,0 +1,127 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type oneOfMessage struct {
+	ID   uint64
+	Body OneOf3[textPayload, imagePayload, docV2]
+}
+
+type oneOfEmbedded struct {
+	OneOf2[textPayload, imagePayload]
+	Trace string
+}
+
+func TestOneOf(t *testing.T) {
+	var v OneOf3[textPayload, imagePayload, docV2]
+	assert.Equal(t, 0, v.Which())
+
+	v.SetB(&imagePayload{Width: 1, Height: 2})
+	assert.Equal(t, 2, v.Which())
+	image, ok := v.B()
+	assert.True(t, ok)
+	assert.Equal(t, &imagePayload{Width: 1, Height: 2}, image)
+	text, ok := v.A()
+	assert.False(t, ok)
+	assert.Nil(t, text)
+
+	v.SetA(&textPayload{Msg: "hi"})
+	assert.Equal(t, 1, v.Which())
+	_, ok = v.B()
+	assert.False(t, ok)
+
+	v.SetC(nil)
+	assert.Equal(t, 0, v.Which())
+	v.SetC(&docV2{Title: "a"})
+	v.Reset()
+	assert.Equal(t, 0, v.Which())
+}
+
+func TestOneOfCodec(t *testing.T) {
+	for _, in := range []oneOfMessage{
+		{ID: 1},
+		{ID: 2, Body: oneOf3(func(v *OneOf3[textPayload, imagePayload, docV2]) { v.SetA(&textPayload{Msg: "hi"}) })},
+		{ID: 3, Body: oneOf3(func(v *OneOf3[textPayload, imagePayload, docV2]) { v.SetB(&imagePayload{Width: 3}) })},
+		{ID: 4, Body: oneOf3(func(v *OneOf3[textPayload, imagePayload, docV2]) { v.SetC(&docV2{Title: "a", Body: "b"}) })},
+	} {
+		// Values that are not addressable encode the same way
+		b, err := Marshal(in)
+		assert.NoError(t, err)
+		p, err := Marshal(&in)
+		assert.NoError(t, err)
+		assert.Equal(t, b, p)
+
+		var out oneOfMessage
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+	}
+}
+
+func TestOneOfWire(t *testing.T) {
+	var v OneOf2[textPayload, imagePayload]
+	v.SetB(&imagePayload{Width: 3, Height: 4})
+	b, err := Marshal(&v)
+	assert.NoError(t, err)
+
+	// The framing is the one of an equivalent tagged union struct
+	expect, err := Marshal(&payload{Image: &imagePayload{Width: 3, Height: 4}})
+	assert.NoError(t, err)
+	assert.Equal(t, expect, b)
+
+	var union payload
+	assert.NoError(t, Unmarshal(b, &union))
+	assert.Equal(t, &imagePayload{Width: 3, Height: 4}, union.Image)
+
+	// Decoding into the same arm reuses its value
+	image, _ := v.B()
+	assert.NoError(t, Unmarshal(b, &v))
+	reused, _ := v.B()
+	assert.True(t, image == reused)
+
+	// Tags beyond the arms are skipped
+	b, err = Marshal(&oneOfMessage{Body: oneOf3(func(v *OneOf3[textPayload, imagePayload, docV2]) { v.SetC(&docV2{}) })})
+	assert.NoError(t, err)
+	var narrow struct {
+		ID   uint64
+		Body OneOf2[textPayload, imagePayload]
+	}
+	narrow.Body.SetA(&textPayload{})
+	assert.NoError(t, Unmarshal(b, &narrow))
+	assert.Equal(t, 0, narrow.Body.Which())
+
+	assert.Error(t, Unmarshal([]byte{1, 5}, &v))
+	assert.Equal(t, 2, v.Which())
+	assert.Error(t, Unmarshal([]byte{2, 1, 0x80}, &v))
+	assert.Equal(t, 0, v.Which())
+}
+
+func TestOneOfEmbedded(t *testing.T) {
+	in := oneOfEmbedded{Trace: "abc"}
+	in.SetA(&textPayload{Msg: "hi"})
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	var out oneOfEmbedded
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+	assert.True(t, isBuiltin(reflect.TypeOf(in.OneOf2)))
+	assert.False(t, isBuiltin(reflect.TypeOf(in)))
+}
+
+func TestOneOfScan(t *testing.T) {
+	_, err := Marshal(&OneOf2[textPayload, chan int]{})
+	assert.Error(t, err)
+}
+
+func oneOf3(fn func(*OneOf3[textPayload, imagePayload, docV2])) (v OneOf3[textPayload, imagePayload, docV2]) {
+	fn(&v)
+	return
+}
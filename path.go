@@ -0,0 +1,613 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPathNotFound is returned by UnmarshalPath when the value the path points to is
+// absent from the input, such as an index out of range, a missing map key, a nil
+// pointer or a union holding another arm.
+var ErrPathNotFound = errors.New("binary: path not found")
+
+// UnmarshalPath decodes into out the value found at the path within b, which holds an
+// encoded value of the type of typ. Preceding fields and elements are skipped rather
+// than decoded. The path is made of field names separated by dots, slice and array
+// indexes and map keys in brackets, for example "Meta.Tags[env]" or "Items[3].Name".
+// Union arms are selected by field name, and out must be a pointer to the type of the
+// value found at the path.
+func UnmarshalPath(b []byte, typ any, path string, out any) error {
+	t := reflect.TypeOf(typ)
+	if t == nil {
+		return errors.New("binary: cannot decode path of nil type")
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("binary: can only decode path to pointer type")
+	}
+
+	var shapeBuffer [128]byte
+	var argsBuffer [8]string
+	shape, args := pathShape(shapeBuffer[:0], argsBuffer[:0], path)
+	plan, err := loadPath(t, shape)
+	if err != nil {
+		return err
+	}
+	if rv.Elem().Type() != plan.target {
+		return errors.New("binary: cannot decode " + plan.target.String() + " into " + rv.Elem().Type().String())
+	}
+
+	d := decoders.Get().(*Decoder)
+	d.reader.(*sliceReader).Reset(b)
+	d.arena = nil
+	err = plan.run(d, rv.Elem(), args)
+	d.arena = nil
+	decoders.Put(d)
+	return err
+}
+
+// pathShape appends the path to shape without the contents of its brackets, which are
+// appended to args, so that paths only differing by their indexes and keys share a plan.
+func pathShape(shape []byte, args []string, path string) ([]byte, []string) {
+	for {
+		start := strings.IndexByte(path, '[')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path[start:], ']')
+		if end < 0 {
+			break
+		}
+		shape = append(shape, path[:start+1]...)
+		args = append(args, path[start+1:start+end])
+		path = path[start+end:]
+	}
+	return append(shape, path...), args
+}
+
+var (
+	pathLock  sync.Mutex
+	pathPlans atomic.Pointer[map[reflect.Type]map[string]*pathPlan] // replaced when a plan is added
+)
+
+func loadPath(t reflect.Type, shape []byte) (*pathPlan, error) {
+	if plans := pathPlans.Load(); plans != nil {
+		if plan, ok := (*plans)[t][string(shape)]; ok {
+			return plan, nil
+		}
+	}
+	plan, err := compilePath(t, string(shape))
+	if err != nil {
+		return nil, err
+	}
+
+	pathLock.Lock()
+	defer pathLock.Unlock()
+	next := make(map[reflect.Type]map[string]*pathPlan)
+	if plans := pathPlans.Load(); plans != nil {
+		for typ, shapes := range *plans {
+			next[typ] = shapes
+		}
+	}
+	shapes := make(map[string]*pathPlan, len(next[t])+1)
+	for k, v := range next[t] {
+		shapes[k] = v
+	}
+	shapes[string(shape)] = plan
+	next[t] = shapes
+	pathPlans.Store(&next)
+	return plan, nil
+}
+
+// ------------------------------------------------------------------------------
+
+type pathSegment struct {
+	name    string
+	bracket bool // an index or a key rather than a field name
+	arg     int  // position of the index or key among the arguments of the path
+}
+
+// parsePath splits the shape of a path, whose brackets are empty, into its segments.
+func parsePath(path string) ([]pathSegment, error) {
+	invalid := errors.New("binary: invalid path " + strconv.Quote(path))
+	var out []pathSegment
+	var args int
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '[':
+			if !strings.HasPrefix(path[i:], "[]") {
+				return nil, invalid
+			}
+			out = append(out, pathSegment{bracket: true, arg: args})
+			args++
+			i += 2
+		case '.':
+			if len(out) == 0 || i+1 == len(path) || path[i+1] == '.' || path[i+1] == '[' {
+				return nil, invalid
+			}
+			i++
+		default:
+			if i > 0 && path[i-1] != '.' {
+				return nil, invalid
+			}
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			out = append(out, pathSegment{name: path[i : i+end]})
+			i += end
+		}
+	}
+	if len(out) == 0 {
+		return nil, invalid
+	}
+	return out, nil
+}
+
+// ------------------------------------------------------------------------------
+
+type pathOp uint8
+
+const (
+	opDeref pathOp = iota // read the nil flag of a pointer
+	opSkip                // skip the preceding fields of a struct
+	opArm                 // enter the body of a union arm
+	opIndex               // skip the preceding elements of a collection
+	opKey                 // seek the value of a map key
+)
+
+type pathKind uint8
+
+const (
+	keyReflect pathKind = iota
+	keyString           // string key of stringMapCodec
+	keyUint64           // fixed-width key of uint64MapCodec
+)
+
+type skipItem struct {
+	codec Codec
+	typ   reflect.Type
+}
+
+type pathStep struct {
+	op     pathOp
+	skips  []skipItem // preceding fields
+	tag    uint64     // union arm tag
+	arg    int        // position of the index or key among the arguments
+	length int        // length of a fixed-length collection, or -1 if it has a length prefix
+	size   int        // fixed element size, or 0 to skip elements one by one
+	elem   skipItem   // element, or map value
+	kind   pathKind   // map key encoding
+	key    reflect.Type
+	codec  *reflectMapCodec
+}
+
+// pathPlan is a path compiled against a type: the steps seeking to the value, the codec
+// of the value, and the segments to resolve after decoding it when its codec cannot be
+// navigated.
+type pathPlan struct {
+	steps  []pathStep
+	codec  Codec
+	typ    reflect.Type
+	rest   []pathSegment
+	target reflect.Type
+}
+
+func compilePath(t reflect.Type, path string) (*pathPlan, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := scan(t)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := new(pathPlan)
+	for i, seg := range segments {
+		for {
+			pointer, ok := codec.(*reflectPointerCodec)
+			if !ok {
+				break
+			}
+			plan.steps = append(plan.steps, pathStep{op: opDeref})
+			codec, t = pointer.elemCodec, t.Elem()
+		}
+
+		var ok bool
+		if seg.bracket {
+			codec, t, ok, err = plan.compileBracket(codec, t, seg.arg)
+		} else {
+			codec, t, ok, err = plan.compileField(codec, t, seg.name)
+		}
+		switch {
+		case err != nil:
+			return nil, err
+		case !ok:
+			plan.rest = segments[i:]
+			plan.codec, plan.typ = codec, t
+			plan.target, err = resolveType(t, plan.rest)
+			return plan, err
+		}
+	}
+	plan.codec, plan.typ, plan.target = codec, t, t
+	return plan, nil
+}
+
+func (p *pathPlan) compileField(codec Codec, t reflect.Type, name string) (Codec, reflect.Type, bool, error) {
+	var fields *reflectStructCodec
+	var union *reflectUnionCodec
+	switch c := codec.(type) {
+	case *reflectStructCodec:
+		fields = c
+	case *reflectMixedCodec:
+		fields, union = c.fields, c.union
+	case *reflectUnionCodec:
+		union = c
+	default:
+		return codec, t, false, nil
+	}
+
+	field, ok := t.FieldByName(name)
+	if !ok || len(field.Index) != 1 {
+		return nil, nil, false, errors.New("binary: no field " + name + " in " + t.String())
+	}
+
+	// Plain fields come first, followed by the union frame
+	var skips []skipItem
+	if fields != nil {
+		for i, f := range *fields {
+			if f.Field&fieldIncluded == 0 {
+				continue
+			}
+			if i == field.Index[0] {
+				p.steps = append(p.steps, pathStep{op: opSkip, skips: skips})
+				return f.Codec, t.Field(i).Type, true, nil
+			}
+			skips = append(skips, skipItem{codec: f.Codec, typ: t.Field(i).Type})
+		}
+	}
+	if union != nil {
+		for _, arm := range union.arms {
+			if arm.index == field.Index[0] {
+				p.steps = append(p.steps, pathStep{op: opSkip, skips: skips}, pathStep{op: opArm, tag: arm.tag})
+				return arm.codec, arm.elem, true, nil
+			}
+		}
+	}
+	return nil, nil, false, errors.New("binary: field " + name + " of " + t.String() + " is not encoded")
+}
+
+func (p *pathPlan) compileBracket(codec Codec, t reflect.Type, arg int) (Codec, reflect.Type, bool, error) {
+	switch c := codec.(type) {
+	case *reflectMapCodec, stringMapCodec[string], stringMapCodec[[]byte], stringMapCodec[uint64], *uint64MapCodec:
+		if !isKeyKind(t.Key().Kind()) {
+			return nil, nil, false, errors.New("binary: unsupported key type " + t.Key().String())
+		}
+		step := pathStep{op: opKey, arg: arg, key: t.Key()}
+		var value Codec = new(primitiveCodec)
+		switch c := c.(type) {
+		case *reflectMapCodec:
+			step.codec, value = c, c.val
+		case stringMapCodec[[]byte]:
+			step.kind, value = keyString, new(byteSliceCodec)
+		case stringMapCodec[string], stringMapCodec[uint64]:
+			step.kind = keyString
+		case *uint64MapCodec:
+			step.kind = keyUint64
+		}
+		step.elem = skipItem{codec: value, typ: t.Elem()}
+		p.steps = append(p.steps, step)
+		return value, t.Elem(), true, nil
+	}
+
+	elem, size, ok := elementCodec(codec)
+	if !ok {
+		return codec, t, false, nil
+	}
+	step := pathStep{op: opIndex, arg: arg, length: -1, size: size}
+	if t.Kind() == reflect.Array {
+		step.length = t.Len()
+	}
+	step.elem = skipItem{codec: elem, typ: t.Elem()}
+	p.steps = append(p.steps, step)
+	return elem, t.Elem(), true, nil
+}
+
+// isKeyKind reports whether map keys of the kind can be written in a path.
+func isKeyKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseIndex parses the index of an element, found in a path.
+func parseIndex(s string) (int, error) {
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return 0, errors.New("binary: invalid index " + strconv.Quote(s))
+	}
+	return index, nil
+}
+
+func parseKey(s string, t reflect.Type) (reflect.Value, error) {
+	key := reflect.New(t).Elem()
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		key.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v int64
+		if v, err = strconv.ParseInt(s, 10, t.Bits()); err == nil {
+			key.SetInt(v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var v uint64
+		if v, err = strconv.ParseUint(s, 10, t.Bits()); err == nil {
+			key.SetUint(v)
+		}
+	case reflect.Bool:
+		var v bool
+		if v, err = strconv.ParseBool(s); err == nil {
+			key.SetBool(v)
+		}
+	default:
+		err = errors.New("unsupported key type")
+	}
+	if err != nil {
+		return reflect.Value{}, errors.New("binary: invalid key " + strconv.Quote(s) + " for " + t.String())
+	}
+	return key, nil
+}
+
+// resolveType returns the type found at the segments within a value of type t.
+func resolveType(t reflect.Type, segments []pathSegment) (reflect.Type, error) {
+	for _, seg := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case !seg.bracket && t.Kind() == reflect.Struct:
+			field, ok := t.FieldByName(seg.name)
+			if !ok {
+				return nil, errors.New("binary: no field " + seg.name + " in " + t.String())
+			}
+			t = field.Type
+		case seg.bracket && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			t = t.Elem()
+		case seg.bracket && t.Kind() == reflect.Map:
+			if !isKeyKind(t.Key().Kind()) {
+				return nil, errors.New("binary: unsupported key type " + t.Key().String())
+			}
+			t = t.Elem()
+		case seg.bracket:
+			return nil, errors.New("binary: cannot index into " + t.String())
+		default:
+			return nil, errors.New("binary: cannot resolve " + strconv.Quote(seg.name) + " in " + t.String())
+		}
+	}
+	return t, nil
+}
+
+// ------------------------------------------------------------------------------
+
+func (p *pathPlan) run(d *Decoder, out reflect.Value, args []string) error {
+	for i := range p.steps {
+		if err := p.steps[i].apply(d, args); err != nil {
+			return err
+		}
+	}
+	if p.rest == nil {
+		return p.codec.DecodeTo(d, out)
+	}
+
+	// The codec cannot be navigated, so the value is decoded and walked instead
+	v := reflect.New(p.typ).Elem()
+	if err := p.codec.DecodeTo(d, v); err != nil {
+		return err
+	}
+	for _, seg := range p.rest {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ErrPathNotFound
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(seg.name)
+		case reflect.Slice, reflect.Array:
+			index, err := parseIndex(args[seg.arg])
+			switch {
+			case err != nil:
+				return err
+			case index >= v.Len():
+				return ErrPathNotFound
+			}
+			v = v.Index(index)
+		case reflect.Map:
+			key, err := parseKey(args[seg.arg], v.Type().Key())
+			if err != nil {
+				return err
+			}
+			if v = v.MapIndex(key); !v.IsValid() {
+				return ErrPathNotFound
+			}
+		}
+	}
+	out.Set(v)
+	return nil
+}
+
+func (s *pathStep) apply(d *Decoder, args []string) error {
+	switch s.op {
+	case opDeref:
+		isNil, err := d.ReadBool()
+		if err == nil && isNil {
+			return ErrPathNotFound
+		}
+		return err
+	case opSkip:
+		for _, item := range s.skips {
+			if err := skipValue(d, item.codec, item.typ); err != nil {
+				return err
+			}
+		}
+		return nil
+	case opArm:
+		tag, body, err := d.ReadTagged()
+		switch {
+		case err != nil:
+			return err
+		case tag != s.tag:
+			return ErrPathNotFound
+		}
+		d.slice.Reset(body)
+		return nil
+	case opIndex:
+		return s.seekIndex(d, args[s.arg])
+	default:
+		return s.seekKey(d, args[s.arg])
+	}
+}
+
+func (s *pathStep) seekIndex(d *Decoder, arg string) error {
+	index, err := parseIndex(arg)
+	if err != nil {
+		return err
+	}
+	if s.length >= 0 {
+		if index >= s.length {
+			return ErrPathNotFound
+		}
+	} else {
+		n, err := d.ReadUvarint()
+		if err != nil {
+			return err
+		}
+		if uint64(index) >= n {
+			return ErrPathNotFound
+		}
+	}
+	if s.size > 0 {
+		return d.skipBytes(uint64(index) * uint64(s.size))
+	}
+	for range index {
+		if err := skipValue(d, s.elem.codec, s.elem.typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pathStep) seekKey(d *Decoder, arg string) error {
+	var want reflect.Value
+	var wantUint uint64
+	var err error
+	switch s.kind {
+	case keyUint64:
+		if wantUint, err = strconv.ParseUint(arg, 10, s.key.Bits()); err != nil {
+			return errors.New("binary: invalid key " + strconv.Quote(arg) + " for " + s.key.String())
+		}
+	case keyReflect:
+		if want, err = parseKey(arg, s.key); err != nil {
+			return err
+		}
+	}
+
+	l, err := d.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	n, err := decodeLength(l)
+	if err != nil {
+		return err
+	}
+
+	var scratch reflect.Value
+	if s.kind == keyReflect {
+		scratch = reflect.New(s.key).Elem()
+	}
+	for range n {
+		var match bool
+		switch s.kind {
+		case keyString:
+			var size uint16
+			var b []byte
+			if size, err = d.ReadUint16(); err == nil {
+				b, err = d.Slice(int(size))
+			}
+			match = err == nil && bytes.Equal(b, ToBytes(arg))
+		case keyUint64:
+			var key uint64
+			key, err = d.ReadUint64()
+			match = err == nil && key == wantUint
+		default:
+			err = s.codec.readKey(d, scratch, nil)
+			match = err == nil && scratch.Equal(want)
+		}
+		switch {
+		case err != nil:
+			return err
+		case match:
+			return nil
+		}
+		if err = skipValue(d, s.elem.codec, s.elem.typ); err != nil {
+			return err
+		}
+	}
+	return ErrPathNotFound
+}
+
+// elementCodec returns the codec of a single element of a collection encoded by the
+// codec, and the size of each element if they are written with a fixed size.
+func elementCodec(codec Codec) (elem Codec, size int, ok bool) {
+	switch c := codec.(type) {
+	case *reflectCollectionCodec:
+		return c.elemCodec, 0, true
+	case *reflectSliceOfPtrCodec:
+		return &reflectPointerCodec{elemCodec: c.elemCodec}, 0, true
+	case *byteSliceCodec:
+		return new(byteCodec), 1, true
+	case *boolSliceCodec:
+		return new(primitiveCodec), 1, true
+	case *fixedSliceCodec:
+		return new(primitiveCodec), int(c.elemSize), true
+	case *stringSliceCodec, *varSliceCodec:
+		return new(primitiveCodec), 0, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// byteCodec decodes a single element of a byte slice, which is written as a raw byte.
+type byteCodec struct{}
+
+func (byteCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	e.Write([]byte{byte(rv.Uint())})
+	return e.err
+}
+
+func (byteCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	b, err := d.reader.ReadByte()
+	if err == nil {
+		rv.SetUint(uint64(b))
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pathMeta struct {
+	Tenant string
+	Region string
+}
+
+type pathItem struct {
+	Name  string
+	Count int
+}
+
+type pathCustom struct {
+	Name string
+}
+
+func (c pathCustom) MarshalBinary() ([]byte, error) { return []byte(c.Name), nil }
+func (c *pathCustom) UnmarshalBinary(b []byte) error {
+	c.Name = string(b)
+	return nil
+}
+
+type pathEnvelope struct {
+	ID     uint64
+	Meta   *pathMeta
+	Items  []pathItem
+	Ptrs   []*pathItem
+	Names  []string
+	Scores []float64
+	Flags  []bool
+	Counts []uint32
+	Blob   []byte
+	Grid   [3]int16
+	Tags   map[string]string
+	Sizes  map[string]uint64
+	IDs    map[uint64]uint64
+	ByID   map[int32]pathItem
+	Custom pathCustom
+	Body   payload
+	Last   string
+}
+
+func newPathEnvelope() *pathEnvelope {
+	return &pathEnvelope{
+		ID:     7,
+		Meta:   &pathMeta{Tenant: "acme", Region: "eu"},
+		Items:  []pathItem{{"a", 1}, {"b", 2}, {"c", 3}},
+		Ptrs:   []*pathItem{nil, {"p", 9}},
+		Names:  []string{"x", "y", "z"},
+		Scores: []float64{1.5, 2.5},
+		Flags:  []bool{false, true},
+		Counts: []uint32{300, 70000},
+		Blob:   []byte{1, 2, 3},
+		Grid:   [3]int16{4, 5, 6},
+		Tags:   map[string]string{"env": "prod", "team": "core"},
+		Sizes:  map[string]uint64{"small": 1, "large": 1000},
+		IDs:    map[uint64]uint64{1: 10, 2: 20},
+		ByID:   map[int32]pathItem{-1: {"neg", 1}, 5: {"five", 5}},
+		Custom: pathCustom{Name: "custom"},
+		Body:   payload{Image: &imagePayload{Width: 3, Height: 4}},
+		Last:   "end",
+	}
+}
+
+func TestUnmarshalPath(t *testing.T) {
+	b, err := Marshal(newPathEnvelope())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path   string
+		expect any
+	}{
+		{"ID", uint64(7)},
+		{"Meta", &pathMeta{Tenant: "acme", Region: "eu"}},
+		{"Meta.Region", "eu"},
+		{"Items[2].Name", "c"},
+		{"Items[1]", pathItem{"b", 2}},
+		{"Ptrs[1].Count", 9},
+		{"Names[1]", "y"},
+		{"Scores[1]", 2.5},
+		{"Flags[1]", true},
+		{"Counts[1]", uint32(70000)},
+		{"Blob[2]", byte(3)},
+		{"Grid[2]", int16(6)},
+		{"Tags[team]", "core"},
+		{"Sizes[large]", uint64(1000)},
+		{"IDs[2]", uint64(20)},
+		{"ByID[5].Name", "five"},
+		{"ByID[-1]", pathItem{"neg", 1}},
+		{"Custom.Name", "custom"},
+		{"Body.Image.Height", 4},
+		{"Last", "end"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			out := reflect.New(reflect.TypeOf(tc.expect))
+			assert.NoError(t, UnmarshalPath(b, pathEnvelope{}, tc.path, out.Interface()))
+			assert.Equal(t, tc.expect, out.Elem().Interface())
+		})
+	}
+}
+
+func TestUnmarshalPathNotFound(t *testing.T) {
+	in := newPathEnvelope()
+	in.Meta = nil
+	b, err := Marshal(in)
+	assert.NoError(t, err)
+
+	for _, path := range []string{
+		"Meta.Tenant",
+		"Items[3]",
+		"Ptrs[0].Name",
+		"Blob[3]",
+		"Grid[3]",
+		"Tags[missing]",
+		"IDs[3]",
+		"ByID[6]",
+		"Body.Text.Msg",
+	} {
+		var out any
+		switch path {
+		case "Items[3]", "ByID[6]":
+			out = new(pathItem)
+		case "Blob[3]":
+			out = new(byte)
+		case "Grid[3]":
+			out = new(int16)
+		case "IDs[3]":
+			out = new(uint64)
+		default:
+			out = new(string)
+		}
+		err := UnmarshalPath(b, pathEnvelope{}, path, out)
+		assert.True(t, errors.Is(err, ErrPathNotFound), path)
+	}
+}
+
+func TestUnmarshalPathInvalid(t *testing.T) {
+	b, err := Marshal(newPathEnvelope())
+	assert.NoError(t, err)
+
+	var s string
+	for _, path := range []string{"", ".ID", "ID.", "Meta..Tenant", "Items[1", "Items[x].Name", "Items[1]Name", "Nope", "Tags.Name", "ID[1]", "ByID[x]"} {
+		err := UnmarshalPath(b, pathEnvelope{}, path, &s)
+		assert.Error(t, err, path)
+		assert.False(t, errors.Is(err, ErrPathNotFound), path)
+	}
+
+	var n int
+	assert.Error(t, UnmarshalPath(b, pathEnvelope{}, "Meta.Tenant", &n))
+	assert.Error(t, UnmarshalPath(b, pathEnvelope{}, "Meta.Tenant", s))
+	assert.Error(t, UnmarshalPath(b, nil, "Meta.Tenant", &s))
+	assert.Error(t, UnmarshalPath(b[:10], pathEnvelope{}, "Last", &s))
+}
+
+func TestUnmarshalPathAllocs(t *testing.T) {
+	b, err := Marshal(newPathEnvelope())
+	assert.NoError(t, err)
+
+	var width int
+	assert.NoError(t, UnmarshalPath(b, &pathEnvelope{}, "Body.Image.Width", &width))
+	assert.Equal(t, 3, width)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = UnmarshalPath(b, pathEnvelope{}, "Items[2].Count", &width)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestUnmarshalPathCache(t *testing.T) {
+	b, err := Marshal(newPathEnvelope())
+	assert.NoError(t, err)
+
+	// Paths differing by their keys and indexes share a plan
+	for i := range 100 {
+		var v uint64
+		err := UnmarshalPath(b, pathEnvelope{}, "IDs["+strconv.Itoa(i)+"]", &v)
+		assert.True(t, err == nil || errors.Is(err, ErrPathNotFound))
+		var s string
+		err = UnmarshalPath(b, pathEnvelope{}, "Tags[key"+strconv.Itoa(i)+"]", &s)
+		assert.True(t, errors.Is(err, ErrPathNotFound))
+	}
+	plans := (*pathPlans.Load())[reflect.TypeFor[pathEnvelope]()]
+	assert.Contains(t, plans, "IDs[]")
+	assert.Contains(t, plans, "Tags[]")
+	assert.True(t, len(plans) < 50, "%d plans", len(plans))
+
+	var id uint64
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = UnmarshalPath(b, pathEnvelope{}, "IDs[2]", &id)
+	})
+	assert.Equal(t, float64(0), allocs)
+	assert.Equal(t, uint64(20), id)
+}
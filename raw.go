@@ -0,0 +1,49 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"reflect"
+)
+
+// RawMessage holds an already encoded value of T, similar to json.RawMessage. It is
+// written unchanged and without a length prefix, so it must hold a complete encoding of
+// T, and an empty RawMessage is written as the zero value of T. Decoding captures a copy
+// of the bytes of exactly one value of T.
+type RawMessage[T any] []byte
+
+func (*RawMessage[T]) scanCodec() (Codec, error) {
+	codec, err := scanType(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	return &rawCodec{elem: reflect.TypeFor[T](), codec: codec}, nil
+}
+
+type rawCodec struct {
+	elem  reflect.Type
+	codec Codec
+}
+
+func (c *rawCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	b := rv.Bytes()
+	if len(b) == 0 {
+		return c.codec.EncodeTo(e, reflect.New(c.elem).Elem())
+	}
+	e.Write(b)
+	return e.err
+}
+
+func (c *rawCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	b, err := d.captureValue(c.codec, c.elem)
+	if err != nil {
+		return err
+	}
+	rv.SetBytes(append(make([]byte, 0, len(b)), b...))
+	return nil
+}
+
+func (c *rawCodec) skip(d *Decoder, _ reflect.Type) error {
+	return skipValue(d, c.codec, c.elem)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rawEnvelope struct {
+	ID   uint64
+	Body RawMessage[payload]
+	Tail string
+}
+
+func TestRawMessage(t *testing.T) {
+	body, err := Marshal(&payload{Text: &textPayload{Msg: "cached"}})
+	assert.NoError(t, err)
+
+	// The pre-encoded body is spliced in as it is
+	in := rawEnvelope{ID: 7, Body: body, Tail: "end"}
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	expect, err := Marshal(&envelope{ID: 7, Body: payload{Text: &textPayload{Msg: "cached"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, expect, b[:len(expect)])
+
+	// Decoding captures exactly the bytes of the body, from memory and from a stream
+	var out rawEnvelope
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+
+	out = rawEnvelope{}
+	assert.NoError(t, NewDecoder(onlyReader{bytes.NewReader(b)}).Decode(&out))
+	assert.Equal(t, in, out)
+
+	// The captured bytes are owned
+	clear(b)
+	assert.Equal(t, body, []byte(out.Body))
+}
+
+func TestRawMessageEmpty(t *testing.T) {
+	b, err := Marshal(&rawEnvelope{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 0, 0, 0}, b)
+
+	var out rawEnvelope
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, RawMessage[payload]{0, 0}, out.Body)
+
+	var list []RawMessage[string]
+	b, err = Marshal([]string{"a", "bc"})
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(b, &list))
+	assert.Equal(t, []RawMessage[string]{{1, 'a'}, {2, 'b', 'c'}}, list)
+}
+
+func TestRawMessageErrors(t *testing.T) {
+	var out rawEnvelope
+	assert.Error(t, Unmarshal([]byte{1, 1, 5, 0}, &out))
+
+	_, err := Marshal(&RawMessage[chan int]{})
+	assert.Error(t, err)
+}
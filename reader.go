@@ -6,7 +6,6 @@
 package binary
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
@@ -24,33 +23,31 @@ type reader interface {
 }
 
 func newReader(r io.Reader) reader {
-	if r != nil && isNilInterface(r) {
-		return newSliceReader(nil)
+	if isStream(r) {
+		return newStreamReader(r)
 	}
 	switch v := r.(type) {
-	case nil:
-		return newSliceReader(nil)
 	case *bytes.Buffer:
-		return newSliceReader(v.Bytes())
-	case *sliceReader:
-		return v
-	default:
-		rdr, ok := r.(reader)
-		if !ok {
-			rdr = newStreamReader(r)
+		if v != nil {
+			return newSliceReader(v.Bytes())
+		}
+	case reader:
+		if !isNilInterface(v) {
+			return v
 		}
-		return rdr
 	}
+	return newSliceReader(nil)
 }
 
 // --------------------------------------- Slice Reader ---------------------------------------
 
 type sliceReader struct {
 	buffer []byte
-	offset int // current reading index
+	offset int  // current reading index
+	strict bool // reject non-minimal varints
 }
 
-func newSliceReader(b []byte) *sliceReader { return &sliceReader{b, 0} }
+func newSliceReader(b []byte) *sliceReader { return &sliceReader{buffer: b} }
 
 func (r *sliceReader) Len() int {
 	if n := len(r.buffer) - r.offset; n > 0 {
@@ -100,6 +97,9 @@ func (r *sliceReader) ReadUvarint() (uint64, error) {
 			if shift == 63 && b > 1 {
 				return value, overflow
 			}
+			if b == 0 && shift > 0 && r.strict {
+				return value, ErrNonCanonical
+			}
 			return value | uint64(b)<<shift, nil
 		}
 		value |= uint64(b&0x7f) << shift
@@ -127,6 +127,10 @@ func readVarints[T integer](r *sliceReader, values []T, signed bool) error {
 			continue
 		}
 		if offset < len(buffer) && buffer[offset] < 0x80 {
+			if buffer[offset] == 0 && r.strict {
+				r.offset = offset + 1
+				return ErrNonCanonical
+			}
 			x := uint64(b&0x7f) | uint64(buffer[offset])<<7
 			offset++
 			values[i] = varintValue[T](x, signed)
@@ -145,6 +149,10 @@ func readVarints[T integer](r *sliceReader, values []T, signed bool) error {
 					r.offset = offset
 					return overflow
 				}
+				if b == 0 && r.strict {
+					r.offset = offset
+					return ErrNonCanonical
+				}
 				x |= uint64(b) << s
 				values[i] = varintValue[T](x, signed)
 				goto next
@@ -177,46 +185,209 @@ func (r *sliceReader) Reset(b []byte) { r.buffer, r.offset = b, 0 }
 
 // --------------------------------------- Stream Reader ---------------------------------------
 
-type streamReader struct {
-	Reader
-}
-
 type Reader interface {
 	io.Reader
 	io.ByteReader
 }
 
+const (
+	streamBufferSize = 4 << 10  // initial size of the stream buffer
+	streamSliceLimit = 64 << 10 // larger slices are read into their own buffer
+)
+
+// streamReader reads from an io.Reader through a buffer it owns. Slices it returns point
+// into the buffer, and stay valid until release is called at the start of the next value,
+// unless copying. Sources that are already buffered, such as a bufio.Reader, are read no
+// further than needed so that they are left positioned right after the value.
+type streamReader struct {
+	src    io.Reader
+	buffer []byte // bytes read from the source, unread from offset onwards
+	offset int
+	read   int64 // bytes read from the source so far
+	exact  bool  // only read the bytes needed
+	pinned bool  // slices of the buffer were handed out since the last release
+	copy   bool  // hand out copies instead of slices of the buffer
+	strict bool  // reject non-minimal varints
+}
+
 func newStreamReader(r io.Reader) *streamReader {
-	rdr, ok := r.(Reader)
-	if !ok {
-		rdr = bufio.NewReader(r)
+	s := new(streamReader)
+	s.Reset(r)
+	return s
+}
+
+// isStream reports whether newReader reads from r through a streamReader.
+func isStream(r io.Reader) bool {
+	if r == nil || isNilInterface(r) {
+		return false
+	}
+	switch r.(type) {
+	case *bytes.Buffer, reader:
+		return false
+	}
+	return true
+}
+
+func (r *streamReader) Reset(src io.Reader) {
+	_, buffered := src.(io.ByteReader)
+	r.src = src
+	r.buffer = r.buffer[:0]
+	r.offset = 0
+	r.read = 0
+	r.exact = buffered
+	r.pinned = false
+}
+
+// consumed returns the number of bytes read through the reader.
+func (r *streamReader) consumed() int64 {
+	return r.read - int64(len(r.buffer)-r.offset)
+}
+
+// release lets the buffer be overwritten, invalidating the slices handed out so far.
+func (r *streamReader) release() {
+	r.pinned = false
+}
+
+// fill buffers at least n unread bytes, reading ahead unless the source is buffered.
+func (r *streamReader) fill(n int) error {
+	if len(r.buffer)-r.offset >= n {
+		return nil
+	}
+	if cap(r.buffer)-r.offset < n {
+		r.grow(n)
+	}
+	for empty := 0; ; {
+		end := cap(r.buffer)
+		if r.exact {
+			end = r.offset + n
+		}
+		m, err := r.src.Read(r.buffer[len(r.buffer):end])
+		r.buffer = r.buffer[:len(r.buffer)+m]
+		r.read += int64(m)
+		switch {
+		case len(r.buffer)-r.offset >= n:
+			return nil
+		case err == io.EOF && len(r.buffer) > r.offset:
+			return io.ErrUnexpectedEOF
+		case err != nil:
+			return err
+		case m == 0:
+			if empty++; empty >= 100 {
+				return io.ErrNoProgress
+			}
+		}
+	}
+}
+
+// grow makes room for n unread bytes by moving them to the start of the buffer, or to a
+// new buffer if it is too small or if slices of it were handed out.
+func (r *streamReader) grow(n int) {
+	unread := r.buffer[r.offset:]
+	size := max(cap(r.buffer), streamBufferSize)
+	for size < n {
+		size *= 2
+	}
+	if r.pinned || size > cap(r.buffer) {
+		buffer := make([]byte, len(unread), size)
+		copy(buffer, unread)
+		r.buffer = buffer
+		r.pinned = false
+	} else {
+		r.buffer = r.buffer[:copy(r.buffer, unread)]
+	}
+	r.offset = 0
+}
+
+// peek returns up to n unread bytes without consuming them, along with the error that
+// kept fewer from being buffered.
+func (r *streamReader) peek(n int) ([]byte, error) {
+	err := r.fill(n)
+	return r.buffer[r.offset:min(r.offset+n, len(r.buffer))], err
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := r.fill(1); err != nil {
+		return 0, err
 	}
-	return &streamReader{
-		Reader: rdr,
+	n := copy(p, r.buffer[r.offset:])
+	r.offset += n
+	return n, nil
+}
+
+func (r *streamReader) ReadByte() (byte, error) {
+	if err := r.fill(1); err != nil {
+		return 0, err
 	}
+	b := r.buffer[r.offset]
+	r.offset++
+	return b, nil
 }
 
-func (r *streamReader) Slice(n int) (buffer []byte, err error) {
+func (r *streamReader) Slice(n int) ([]byte, error) {
 	if n < 0 || uint64(n) > uint64(^uint(0)>>1)/2 {
 		return nil, io.ErrUnexpectedEOF
 	}
-	if n > 64<<10 {
-		buffer := bytes.NewBuffer(make([]byte, 0, 64<<10))
-		_, err = buffer.ReadFrom(io.LimitReader(r, int64(n)))
-		if err == nil && buffer.Len() != n {
-			err = io.EOF
-		}
-		return buffer.Bytes(), err
+	if n > streamSliceLimit && n > len(r.buffer)-r.offset {
+		return r.readLarge(n)
 	}
-	buffer = make([]byte, n)
-	_, err = io.ReadFull(r, buffer)
-	return
+	if err := r.fill(n); err != nil {
+		return nil, err
+	}
+	b := r.buffer[r.offset : r.offset+n : r.offset+n]
+	r.offset += n
+	if r.copy {
+		return append(make([]byte, 0, n), b...), nil
+	}
+	r.pinned = true
+	return b, nil
 }
 
+// readLarge reads n bytes into a buffer of their own, which grows as the bytes arrive
+// rather than trusting the length up front.
+func (r *streamReader) readLarge(n int) ([]byte, error) {
+	buffer := bytes.NewBuffer(make([]byte, 0, streamSliceLimit))
+	buffer.Write(r.buffer[r.offset:])
+	r.offset = len(r.buffer)
+	m, err := buffer.ReadFrom(io.LimitReader(r.src, int64(n-buffer.Len())))
+	r.read += m
+	if err == nil && buffer.Len() != n {
+		err = io.EOF
+	}
+	return buffer.Bytes(), err
+}
+
+// ReadUvarint decodes the varint straight from the buffer, refilling it as needed.
 func (r *streamReader) ReadUvarint() (uint64, error) {
-	return binary.ReadUvarint(r)
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if r.offset >= len(r.buffer) {
+			if err := r.fill(1); err != nil {
+				if shift > 0 && err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+		}
+		b := r.buffer[r.offset]
+		r.offset++
+		if b < 0x80 {
+			if shift == 63 && b > 1 {
+				return value, overflow
+			}
+			if b == 0 && shift > 0 && r.strict {
+				return value, ErrNonCanonical
+			}
+			return value | uint64(b)<<shift, nil
+		}
+		value |= uint64(b&0x7f) << shift
+	}
+	return value, overflow
 }
 
 func (r *streamReader) ReadVarint() (int64, error) {
-	return binary.ReadVarint(r)
+	ux, err := r.ReadUvarint()
+	return decodeVarint(ux), err
 }
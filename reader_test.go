@@ -135,6 +135,124 @@ func TestReaderBranches(t *testing.T) {
 	assert.Equal(t, io.EOF, err)
 }
 
+func TestStreamBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for range 100 {
+		assert.NoError(t, e.Encode(newBigStruct()))
+	}
+
+	for name, r := range map[string]func() io.Reader{
+		"network":  func() io.Reader { return newNetworkSource(buf.Bytes()) },
+		"one byte": func() io.Reader { return &oneByteReader{content: buf.Bytes()} },
+		"buffered": func() io.Reader { return bytes.NewReader(buf.Bytes()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := NewDecoder(r())
+			values := make([]bigStruct, 100)
+			for i := range values {
+				assert.NoError(t, d.Decode(&values[i]))
+			}
+
+			// Values decoded earlier are not overwritten as the buffer is reused
+			for i := range values {
+				assert.Equal(t, newBigStruct(), &values[i])
+			}
+			assert.Equal(t, int64(buf.Len()), d.InputOffset())
+			assert.Equal(t, io.EOF, d.Decode(new(bigStruct)))
+		})
+	}
+}
+
+func TestStreamBorrowedSlices(t *testing.T) {
+	input := []byte{3, 'a', 'b', 'c', 3, 'd', 'e', 'f', 3, 'g', 'h', 'i'}
+
+	// Slices read within a value stay valid, even when the buffer is refilled
+	d := NewDecoder(&oneByteReader{content: input})
+	first, err := d.ReadSlice()
+	assert.NoError(t, err)
+	second, err := d.ReadSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(first))
+	assert.Equal(t, "def", string(second))
+	assert.Equal(t, 3, cap(first))
+
+	// Copies remain valid after the next Decode
+	d = NewDecoder(newNetworkSource(input))
+	d.CopySlices()
+	first, err = d.ReadSlice()
+	assert.NoError(t, err)
+	var s string
+	assert.NoError(t, d.Decode(&s))
+	assert.NoError(t, d.Decode(&s))
+	assert.Equal(t, "abc", string(first))
+	assert.Equal(t, "ghi", s)
+}
+
+func TestStreamExactReads(t *testing.T) {
+	b, err := Marshal(&textPayload{Msg: "hello"})
+	assert.NoError(t, err)
+
+	// Buffered sources are left right after the value, other ones are read ahead
+	r := bytes.NewReader(append(b, "tail"...))
+	assert.NoError(t, NewDecoder(r).Decode(new(textPayload)))
+	assert.Equal(t, 4, r.Len())
+
+	network := newNetworkSource(append(b, "tail"...))
+	assert.NoError(t, NewDecoder(network).Decode(new(textPayload)))
+	assert.Equal(t, 0, network.(*networkSource).r.(*bytes.Buffer).Len())
+}
+
+func TestStreamLargeSlice(t *testing.T) {
+	in := bytes.Repeat([]byte{'x'}, 1<<20)
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	var out []byte
+	d := NewDecoder(newNetworkSource(b))
+	assert.NoError(t, d.Decode(&out))
+	assert.Equal(t, in, out)
+	assert.Equal(t, int64(len(b)), d.InputOffset())
+
+	_, err = NewDecoder(newNetworkSource(b[:len(b)-1])).Slice(len(in) + 3)
+	assert.Error(t, err)
+}
+
+func TestDecoderReset(t *testing.T) {
+	b, err := Marshal(newBigStruct())
+	assert.NoError(t, err)
+
+	d := NewDecoder(newNetworkSource(b))
+	d.Strict()
+	out := new(bigStruct)
+	assert.NoError(t, d.Decode(out))
+
+	// The stream buffer and options are kept across inputs
+	item, err := Marshal(&pathItem{Name: "item", Count: 1})
+	assert.NoError(t, err)
+	source := bytes.NewReader(item)
+	var got pathItem
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		source.Reset(item)
+		d.Reset(source)
+		if err := d.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	assert.Equal(t, pathItem{Name: "item", Count: 1}, got)
+	assert.Equal(t, int64(len(item)), d.InputOffset())
+
+	d.Reset(bytes.NewReader([]byte{0x80, 0x00}))
+	assert.Equal(t, ErrNonCanonical, d.Decode(new(uint64)))
+
+	// Other kinds of inputs are picked up too
+	d.Reset(bytes.NewBuffer(b))
+	assert.NoError(t, d.Decode(out))
+	assert.Equal(t, newBigStruct(), out)
+	d.Reset(nil)
+	assert.Equal(t, io.EOF, d.Decode(out))
+}
+
 // --------------------------------------- Big Structure (Every Field Type) ---------------------------------------
 
 // structure with every possible codec type
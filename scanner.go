@@ -4,11 +4,15 @@
 package binary
 
 import (
+	"cmp"
 	"errors"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/kelindar/binary/internal/fields"
 )
 
 var schemas = new(sync.Map)
@@ -27,7 +31,64 @@ func scan(t reflect.Type) (c Codec, err error) {
 	return c, nil
 }
 
+func init() {
+	fields.Encoded = encodedFields
+}
+
+// encodedFields returns the indices of the fields of the struct type t that its codec
+// writes, union arms included, or nil when t is not encoded field by field. The arm of
+// a OneOf is reached through its unexported oneOf field, and then its value field.
+func encodedFields(t reflect.Type) ([]int, error) {
+	switch {
+	case t == nil || t.Kind() != reflect.Struct:
+		return nil, errors.New("binary: can only list the fields of a struct type")
+	case t == reflect.TypeFor[oneOf]():
+		return []int{1}, nil
+	}
+	c, err := scan(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields *reflectStructCodec
+	var union *reflectUnionCodec
+	switch c := c.(type) {
+	case *reflectStructCodec:
+		fields = c
+	case *reflectMixedCodec:
+		fields, union = c.fields, c.union
+	case *reflectUnionCodec:
+		union = c
+	case *oneOfCodec, *versionedCodec:
+		return []int{0}, nil
+	default:
+		return nil, nil
+	}
+
+	out := make([]int, 0, t.NumField())
+	if fields != nil {
+		for i, field := range *fields {
+			if field.Field&fieldIncluded != 0 {
+				out = append(out, i)
+			}
+		}
+	}
+	if union != nil {
+		for _, arm := range union.arms {
+			out = append(out, arm.index)
+		}
+		if union.unknown >= 0 {
+			out = append(out, union.unknown)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
 func scanType(t reflect.Type) (Codec, error) {
+	if isBuiltin(t) {
+		return reflect.New(t).Interface().(builtin).scanCodec()
+	}
 	if custom, ok := scanCustomCodec(t); ok {
 		if custom == nil {
 			return nil, errors.New("binary: GetBinaryCodec returned nil for " + t.String())
@@ -48,6 +109,8 @@ func scanType(t reflect.Type) (Codec, error) {
 		return scanStructCodec(t)
 	case reflect.Map:
 		return scanMap(t)
+	case reflect.Interface:
+		return scanInterface(t)
 	default:
 		if c := scanPrimitive(t.Kind()); c != nil {
 			return c, nil
@@ -56,6 +119,29 @@ func scanType(t reflect.Type) (Codec, error) {
 	}
 }
 
+// builtin is implemented by the generic types of this package, which build their own codec.
+type builtin interface {
+	scanCodec() (Codec, error)
+}
+
+// isBuiltin reports whether the type is one of the builtin types, rather than a struct
+// that inherits the method by embedding one.
+func isBuiltin(t reflect.Type) bool {
+	iface := reflect.TypeFor[builtin]()
+	switch {
+	case !reflect.PointerTo(t).Implements(iface):
+		return false
+	case t.Kind() != reflect.Struct:
+		return true
+	}
+	for i := range t.NumField() {
+		if f := t.Field(i); f.Anonymous && (f.Type.Implements(iface) || reflect.PointerTo(f.Type).Implements(iface)) {
+			return false
+		}
+	}
+	return true
+}
+
 func scanPointer(t reflect.Type) (Codec, error) {
 	elemCodec, err := scanType(t.Elem())
 	if err != nil {
@@ -129,9 +215,11 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 	var (
 		hasTagged bool
 		hasPlain  bool
+		isArm     []bool
 		arms      []unionArm
 		seen      map[uint64]struct{}
 		maxTag    uint64
+		unknown   = -1
 	)
 	for i := range n {
 		field := t.Field(i)
@@ -139,6 +227,12 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 		switch {
 		case field.Name == "_" || field.PkgPath != "" || tag == "-":
 			continue
+		case field.Type == reflect.TypeFor[UnknownArm]() && tag == "":
+			if unknown >= 0 {
+				return nil, errors.New("binary: duplicate unknown arm on " + t.String())
+			}
+			unknown = i
+			continue
 		case tag == "":
 			hasPlain = true
 			continue
@@ -153,7 +247,7 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 		}
 		id, err := strconv.ParseUint(value, 10, 64)
 		switch {
-		case err != nil || id == 0 || id > maxUnionTag:
+		case err != nil || id == 0:
 			return nil, errors.New("binary: invalid tag " + strconv.Quote(tag) + " on " + t.String())
 		case field.Type.Kind() != reflect.Ptr:
 			return nil, errors.New("binary: union arm " + field.Name + " must be a pointer")
@@ -165,6 +259,10 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 			return nil, errors.New("binary: duplicate union tag " + tag + " on " + t.String())
 		}
 		seen[id] = struct{}{}
+		if isArm == nil {
+			isArm = make([]bool, n)
+		}
+		isArm[i] = true
 		hasTagged = true
 		if id > maxTag {
 			maxTag = id
@@ -182,18 +280,31 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 			codec:  codec,
 		})
 	}
+	if hasTagged && unknown >= 0 {
+		isArm[unknown] = true
+	}
 	switch {
 	case hasTagged && hasPlain:
-		return nil, errors.New("binary: mixed union and sequential fields on " + t.String())
+		fields, err := scanFields(t, isArm)
+		if err != nil {
+			return nil, err
+		}
+		return &reflectMixedCodec{fields: fields, union: newUnionCodec(arms, maxTag, unknown)}, nil
 	case hasTagged:
-		return newUnionCodec(arms, maxTag), nil
+		return newUnionCodec(arms, maxTag, unknown), nil
 	}
+	return scanFields(t, nil)
+}
+
+// scanFields builds the sequential codec of a struct, leaving out the union arms.
+func scanFields(t reflect.Type, isArm []bool) (*reflectStructCodec, error) {
+	n := t.NumField()
 	v := make(reflectStructCodec, n)
 	hasDirect := false
 	for i := range n {
 		field := t.Field(i)
 		tag := field.Tag.Get("binary")
-		if field.Name == "_" || field.PkgPath != "" || tag == "-" {
+		if field.Name == "_" || field.PkgPath != "" || tag == "-" || (isArm != nil && isArm[i]) {
 			continue
 		}
 		codec, err := scanType(field.Type)
@@ -237,9 +348,20 @@ func scanStructCodec(t reflect.Type) (Codec, error) {
 	return &v, nil
 }
 
-const maxUnionTag = 255 // wire tags are uvarint; arms must fit a dense 1..255 table (0 = none)
+const maxDenseTag = 255 // tags up to this use a dense lookup table (0 = none), larger ones are searched
+
+func newUnionCodec(arms []unionArm, maxTag uint64, unknown int) *reflectUnionCodec {
+	if maxTag > maxDenseTag {
+		sparse := make([]int, len(arms))
+		for i := range sparse {
+			sparse[i] = i
+		}
+		slices.SortFunc(sparse, func(a, b int) int {
+			return cmp.Compare(arms[a].tag, arms[b].tag)
+		})
+		return &reflectUnionCodec{arms: arms, sparse: sparse, unknown: unknown}
+	}
 
-func newUnionCodec(arms []unionArm, maxTag uint64) *reflectUnionCodec {
 	byTag := make([]int, maxTag+1)
 	for i := range byTag {
 		byTag[i] = -1
@@ -247,7 +369,7 @@ func newUnionCodec(arms []unionArm, maxTag uint64) *reflectUnionCodec {
 	for i := range arms {
 		byTag[arms[i].tag] = i
 	}
-	return &reflectUnionCodec{arms: arms, byTag: byTag}
+	return &reflectUnionCodec{arms: arms, byTag: byTag, unknown: unknown}
 }
 
 func scanMap(t reflect.Type) (Codec, error) {
@@ -58,3 +58,37 @@ type Strings struct {
 	Hash []uint32
 	Data map[uint64][]byte
 }
+
+func TestEncodedFields(t *testing.T) {
+	type mixed struct {
+		ID      int
+		skipped string
+		Ignored string        `binary:"-"`
+		Text    *textPayload  `binary:"1,union"`
+		Image   *imagePayload `binary:"2,union"`
+		Unknown UnknownArm
+	}
+
+	codec, err := scan(reflect.TypeFor[mixed]())
+	assert.NoError(t, err)
+	assert.IsType(t, &reflectMixedCodec{}, codec)
+
+	fields, err := encodedFields(reflect.TypeFor[mixed]())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 3, 4, 5}, fields)
+
+	fields, err = encodedFields(reflect.TypeFor[pathCustom]())
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+
+	// The arm of a OneOf is reached through its unexported state
+	fields, err = encodedFields(reflect.TypeFor[OneOf2[textPayload, imagePayload]]())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, fields)
+	fields, err = encodedFields(reflect.TypeFor[oneOf]())
+	assert.NoError(t, err)
+	assert.Equal(t, "value", reflect.TypeFor[oneOf]().Field(fields[0]).Name)
+
+	_, err = encodedFields(reflect.TypeFor[int]())
+	assert.Error(t, err)
+}
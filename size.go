@@ -0,0 +1,243 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// sizer is implemented by the codecs that can tell the number of bytes a value is
+// encoded with without encoding it, so that a framed value is written only once.
+type sizer interface {
+	size(rv reflect.Value) (int, bool)
+}
+
+// wireSize returns the number of bytes the codec encodes rv with, or false when the
+// codec cannot tell without encoding it, such as a custom or a map one.
+func wireSize(codec Codec, rv reflect.Value) (int, bool) {
+	if s, ok := codec.(sizer); ok {
+		return s.size(rv)
+	}
+	return 0, false
+}
+
+func varintSize(v int64) int {
+	x := uint64(v) << 1
+	if v < 0 {
+		x = ^x
+	}
+	return uvarintSize(x)
+}
+
+// framedSize returns the size of a tag followed by a frame holding n bytes.
+func framedSize(tag uint64, n int) int {
+	return uvarintSize(tag) + uvarintSize(uint64(n)) + n
+}
+
+func (*primitiveCodec) size(rv reflect.Value) (int, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return uvarintSize(uint64(rv.Len())) + rv.Len(), true
+	case reflect.Bool:
+		return 1, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return varintSize(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uvarintSize(rv.Uint()), true
+	case reflect.Float32:
+		return 4, true
+	case reflect.Float64, reflect.Complex64:
+		return 8, true
+	case reflect.Complex128:
+		return 16, true
+	}
+	return 0, true
+}
+
+func (*byteSliceCodec) size(rv reflect.Value) (int, bool) {
+	return uvarintSize(uint64(rv.Len())) + rv.Len(), true
+}
+
+func (*boolSliceCodec) size(rv reflect.Value) (int, bool) {
+	return uvarintSize(uint64(rv.Len())) + rv.Len(), true
+}
+
+func (c *fixedSliceCodec) size(rv reflect.Value) (int, bool) {
+	n := rv.Len() * int(c.elemSize)
+	if !c.array {
+		n += uvarintSize(uint64(rv.Len()))
+	}
+	return n, true
+}
+
+func (c *stringSliceCodec) size(rv reflect.Value) (int, bool) {
+	n := 0
+	if !c.array {
+		n = uvarintSize(uint64(rv.Len()))
+	}
+	for i := range rv.Len() {
+		l := rv.Index(i).Len()
+		n += uvarintSize(uint64(l)) + l
+	}
+	return n, true
+}
+
+func (c *varSliceCodec) size(rv reflect.Value) (int, bool) {
+	l := rv.Len()
+	n := uvarintSize(uint64(l))
+	base := rv.UnsafePointer()
+	switch {
+	case c.signed && c.elemSize == 1:
+		for _, v := range unsafe.Slice((*int8)(base), l) {
+			n += varintSize(int64(v))
+		}
+	case c.signed && c.elemSize == 2:
+		for _, v := range unsafe.Slice((*int16)(base), l) {
+			n += varintSize(int64(v))
+		}
+	case c.signed && c.elemSize == 4:
+		for _, v := range unsafe.Slice((*int32)(base), l) {
+			n += varintSize(int64(v))
+		}
+	case c.signed:
+		for _, v := range unsafe.Slice((*int64)(base), l) {
+			n += varintSize(v)
+		}
+	case c.elemSize == 2:
+		for _, v := range unsafe.Slice((*uint16)(base), l) {
+			n += uvarintSize(uint64(v))
+		}
+	case c.elemSize == 4:
+		for _, v := range unsafe.Slice((*uint32)(base), l) {
+			n += uvarintSize(uint64(v))
+		}
+	default:
+		for _, v := range unsafe.Slice((*uint64)(base), l) {
+			n += uvarintSize(v)
+		}
+	}
+	return n, true
+}
+
+func (c *reflectCollectionCodec) size(rv reflect.Value) (int, bool) {
+	n := 0
+	if !c.array {
+		n = uvarintSize(uint64(rv.Len()))
+	}
+	for i := range rv.Len() {
+		size, ok := wireSize(c.elemCodec, rv.Index(i))
+		if !ok {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+func (c *reflectSliceOfPtrCodec) size(rv reflect.Value) (int, bool) {
+	n := uvarintSize(uint64(rv.Len())) + rv.Len()
+	for i := range rv.Len() {
+		if v := rv.Index(i); !v.IsNil() {
+			size, ok := wireSize(c.elemCodec, v.Elem())
+			if !ok {
+				return 0, false
+			}
+			n += size
+		}
+	}
+	return n, true
+}
+
+func (c *reflectPointerCodec) size(rv reflect.Value) (int, bool) {
+	if rv.IsNil() {
+		return 1, true
+	}
+	n, ok := wireSize(c.elemCodec, rv.Elem())
+	return n + 1, ok
+}
+
+func (c reflectStructCodec) size(rv reflect.Value) (int, bool) {
+	n := 0
+	for i := range c {
+		if c[i].Field&fieldIncluded == 0 {
+			continue
+		}
+		size, ok := wireSize(c[i].Codec, rv.Field(i))
+		if !ok {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+func (c *rawCodec) size(rv reflect.Value) (int, bool) {
+	if rv.Len() == 0 {
+		return wireSize(c.codec, reflect.New(c.elem).Elem())
+	}
+	return rv.Len(), true
+}
+
+func (c *reflectUnionCodec) size(rv reflect.Value) (int, bool) {
+	selected, elem := c.findArm(rv)
+	switch selected {
+	case &errArm:
+		return 0, false
+	case nil:
+		if c.unknown >= 0 {
+			u := c.unknownArm(rv)
+			return framedSize(u.Tag, len(u.Body)), true
+		}
+		return framedSize(0, 0), true
+	}
+	n, ok := wireSize(selected.codec, elem)
+	return framedSize(selected.tag, n), ok
+}
+
+func (c *reflectMixedCodec) size(rv reflect.Value) (int, bool) {
+	fields, ok := c.fields.size(rv)
+	if !ok {
+		return 0, false
+	}
+	union, ok := c.union.size(rv)
+	return fields + union, ok
+}
+
+func (c *oneOfCodec) size(rv reflect.Value) (int, bool) {
+	o := oneOfOf(rv)
+	if o.tag == 0 {
+		return framedSize(0, 0), true
+	}
+	n, ok := wireSize(c.codecs[o.tag-1], reflect.ValueOf(o.value).Elem())
+	return framedSize(uint64(o.tag), n), ok
+}
+
+func (c *versionedCodec) size(rv reflect.Value) (int, bool) {
+	value := rv.Field(0)
+	if value.IsNil() {
+		return framedSize(0, 0), true
+	}
+	n, ok := wireSize(c.codecs[len(c.codecs)-1], value.Elem())
+	return framedSize(uint64(len(c.types)), n), ok
+}
+
+func (c *interfaceCodec) size(rv reflect.Value) (int, bool) {
+	if rv.IsNil() {
+		return framedSize(0, 0), true
+	}
+	value := rv.Elem()
+	i, ok := c.byType[value.Type()]
+	if !ok {
+		return 0, false
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return 0, false
+		}
+		value = value.Elem()
+	}
+	n, ok := wireSize(c.arms[i].codec, value)
+	return framedSize(c.arms[i].tag, n), ok
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sizedValue struct {
+	Name    string
+	Flag    bool
+	Small   int8
+	Large   int64
+	Count   uint
+	Ratio   float32
+	Value   float64
+	Point   complex64
+	Wide    complex128
+	Data    []byte
+	Flags   []bool
+	Floats  []float64
+	Deltas  []int16
+	IDs     []uint32
+	Tags    [2]string
+	Names   []string
+	Next    *textPayload
+	Items   []*textPayload
+	Payload payload
+	Shape   shape
+}
+
+func TestWireSize(t *testing.T) {
+	body, err := Marshal(&payload{Text: &textPayload{Msg: "raw"}})
+	assert.NoError(t, err)
+
+	oneOf := OneOf3[textPayload, imagePayload, docV2]{}
+	oneOf.SetC(&docV2{Title: "a", Body: strings.Repeat("b", 200)})
+	for _, v := range []any{
+		&sizedValue{},
+		&sizedValue{
+			Name: "a", Flag: true, Small: -3, Large: -1 << 40, Count: 300, Ratio: 1, Value: 2,
+			Point: 1 + 2i, Wide: 3 + 4i, Data: []byte("data"), Flags: []bool{true, false},
+			Floats: []float64{1, 2}, Deltas: []int16{-200, 5}, IDs: []uint32{1 << 20},
+			Tags: [2]string{"x", "y"}, Names: []string{"n"}, Next: &textPayload{Msg: "b"},
+			Items:   []*textPayload{{Msg: "m"}, nil},
+			Payload: payload{Image: &imagePayload{Width: 1, Height: -1}},
+			Shape:   &square{Side: 2},
+		},
+		&doc{V2: &docV2{Title: "a", Body: strings.Repeat("b", 300)}},
+		&oneOf,
+		&Versioned[docV3]{Value: &docV3{Title: "v"}},
+		&Versioned[docV3]{},
+		&rawEnvelope{ID: 1, Body: body},
+		&rawEnvelope{ID: 1},
+		&[]int8{-1, 2},
+		&[]int64{-1 << 50},
+		&[]uint64{1 << 50},
+	} {
+		b, err := Marshal(v)
+		assert.NoError(t, err)
+
+		rv := reflect.ValueOf(v).Elem()
+		codec, err := scan(rv.Type())
+		assert.NoError(t, err)
+		size, ok := wireSize(codec, rv)
+		assert.True(t, ok, "%T", v)
+		assert.Equal(t, len(b), size, "%T", v)
+	}
+
+	// Maps and custom marshalers cannot be sized without encoding them
+	for _, v := range []any{map[string]int{"a": 1}, countedValue{}} {
+		codec, err := scan(reflect.TypeOf(v))
+		assert.NoError(t, err)
+		_, ok := wireSize(codec, reflect.ValueOf(v))
+		assert.False(t, ok, "%T", v)
+	}
+}
+
+func TestUnionWrittenDirectly(t *testing.T) {
+	type blob struct {
+		Data []byte
+	}
+	type message struct {
+		Blob *blob `binary:"1,union"`
+	}
+
+	in := message{Blob: &blob{Data: bytes.Repeat([]byte{'x'}, 1000)}}
+	expect, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	// The arm is sized up front, so its body reaches the writer without being buffered
+	var out countingWriter
+	assert.NoError(t, NewEncoder(struct{ io.Writer }{&out}).Encode(&in))
+	assert.Equal(t, []int{1, 2, 2, 1000}, out.writes)
+	assert.Equal(t, expect, out.Bytes())
+}
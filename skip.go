@@ -0,0 +1,420 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"encoding/binary"
+	"reflect"
+)
+
+// skipper is implemented by the codecs that can step over a value of type t without
+// decoding it, while checking that the value is well formed.
+type skipper interface {
+	skip(d *Decoder, t reflect.Type) error
+}
+
+// skipValue steps over a value of type t encoded with the codec, falling back to decoding
+// it into a throwaway value for codecs that cannot skip, such as custom ones.
+func skipValue(d *Decoder, codec Codec, t reflect.Type) error {
+	if s, ok := codec.(skipper); ok {
+		return s.skip(d, t)
+	}
+	return codec.DecodeTo(d, reflect.New(t).Elem())
+}
+
+// skipBody steps over a value of type t held in a union arm body.
+func skipBody(d *Decoder, body []byte, codec Codec, t reflect.Type) error {
+	dec := d.borrow(body)
+	err := skipValue(dec, codec, t)
+	release(dec)
+	return err
+}
+
+// captureValue steps over a value of type t and returns the bytes it is encoded with,
+// which are only valid until the next read.
+func (d *Decoder) captureValue(codec Codec, t reflect.Type) ([]byte, error) {
+	if d.slice != nil {
+		start := d.slice.offset
+		if err := skipValue(d, codec, t); err != nil {
+			return nil, err
+		}
+		return d.slice.buffer[start:d.slice.offset], nil
+	}
+
+	capture := &captureReader{reader: d.reader, strict: d.flags&flagStrict != 0}
+	d.reader = capture
+	err := skipValue(d, codec, t)
+	d.reader = capture.reader
+	return capture.data, err
+}
+
+func (d *Decoder) skipBytes(n uint64) error {
+	_, err := d.readSlice(n)
+	return err
+}
+
+func (d *Decoder) skipLength(t reflect.Type) (int, error) {
+	l, err := d.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+	n, err := decodeLength(l)
+	if err != nil {
+		return 0, err
+	}
+	return n, validateSliceLength(t, n)
+}
+
+func (d *Decoder) skipSlice(t reflect.Type, elemSize int) error {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	return d.skipBytes(uint64(n) * uint64(elemSize))
+}
+
+// captureReader records every byte read from the underlying stream.
+type captureReader struct {
+	reader reader
+	data   []byte
+	strict bool // reject non-minimal varints
+}
+
+func (r *captureReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.data = append(r.data, p[:n]...)
+	return
+}
+
+func (r *captureReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.data = append(r.data, b)
+	}
+	return b, err
+}
+
+func (r *captureReader) Slice(n int) ([]byte, error) {
+	b, err := r.reader.Slice(n)
+	r.data = append(r.data, b...)
+	return b, err
+}
+
+func (r *captureReader) ReadUvarint() (uint64, error) {
+	start := len(r.data)
+	value, err := binary.ReadUvarint(r)
+	if err == nil && r.strict && len(r.data)-start != uvarintSize(value) {
+		return value, ErrNonCanonical
+	}
+	return value, err
+}
+
+func (r *captureReader) ReadVarint() (int64, error) {
+	ux, err := r.ReadUvarint()
+	return decodeVarint(ux), err
+}
+
+// ------------------------------------------------------------------------------
+
+func (*primitiveCodec) skip(d *Decoder, t reflect.Type) (err error) {
+	switch t.Kind() {
+	case reflect.String:
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil {
+			err = d.skipBytes(l)
+		}
+	case reflect.Bool:
+		_, err = d.ReadBool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = d.ReadVarint()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = d.ReadUvarint()
+	case reflect.Float32:
+		err = d.skipBytes(4)
+	case reflect.Float64, reflect.Complex64:
+		err = d.skipBytes(8)
+	case reflect.Complex128:
+		err = d.skipBytes(16)
+	}
+	return
+}
+
+func (c *reflectCollectionCodec) skip(d *Decoder, t reflect.Type) (err error) {
+	n := c.length
+	if !c.array {
+		if n, err = d.skipLength(t); err != nil {
+			return
+		}
+	}
+	if isZeroWireCodec(c.elemCodec) {
+		return nil
+	}
+	if err = d.ensureElements(n, wireMinBytes(c.elemCodec)); err != nil {
+		return
+	}
+	for range n {
+		if err = skipValue(d, c.elemCodec, t.Elem()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *reflectSliceOfPtrCodec) skip(d *Decoder, t reflect.Type) (err error) {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	if err = d.ensureAvailable(n); err != nil {
+		return err
+	}
+	for range n {
+		isNil, err := d.ReadBool()
+		switch {
+		case err != nil:
+			return err
+		case isNil:
+			continue
+		}
+		if err = skipValue(d, c.elemCodec, c.elemType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*byteSliceCodec) skip(d *Decoder, t reflect.Type) error {
+	return d.skipSlice(t, 1)
+}
+
+func (*boolSliceCodec) skip(d *Decoder, t reflect.Type) error {
+	return d.skipSlice(t, 1)
+}
+
+func (c *fixedSliceCodec) skip(d *Decoder, t reflect.Type) error {
+	if c.array {
+		return d.skipBytes(uint64(c.length) * uint64(c.elemSize))
+	}
+	return d.skipSlice(t, int(c.elemSize))
+}
+
+func (c *stringSliceCodec) skip(d *Decoder, t reflect.Type) (err error) {
+	n := c.length
+	if !c.array {
+		if n, err = d.skipLength(t); err != nil {
+			return
+		}
+	}
+	if err = d.ensureAvailable(n); err != nil {
+		return
+	}
+	for range n {
+		var l uint64
+		if l, err = d.ReadUvarint(); err != nil {
+			return
+		}
+		if err = d.skipBytes(l); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *varSliceCodec) skip(d *Decoder, t reflect.Type) (err error) {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	if err = d.ensureAvailable(n); err != nil {
+		return err
+	}
+	for range n {
+		if _, err = d.ReadUvarint(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *reflectPointerCodec) skip(d *Decoder, t reflect.Type) error {
+	isNil, err := d.ReadBool()
+	if err != nil || isNil {
+		return err
+	}
+	return skipValue(d, c.elemCodec, t.Elem())
+}
+
+func (c reflectStructCodec) skip(d *Decoder, t reflect.Type) error {
+	for i := range c {
+		if c[i].Field&fieldIncluded == 0 {
+			continue
+		}
+		if err := skipValue(d, c[i].Codec, t.Field(i).Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *customCodec) skip(d *Decoder, t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		isNil, err := d.ReadBool()
+		if err != nil || isNil {
+			return err
+		}
+	}
+	return d.skipSlice(reflect.TypeFor[[]byte](), 1)
+}
+
+func (stringMapCodec[V]) skip(d *Decoder, t reflect.Type) error {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	if err = d.ensureElements(n, 3); err != nil {
+		return err
+	}
+	for range n {
+		size, err := d.ReadUint16()
+		if err != nil {
+			return err
+		}
+		if err = d.skipBytes(uint64(size)); err != nil {
+			return err
+		}
+		var zero V
+		if _, ok := any(zero).(uint64); ok {
+			_, err = d.ReadUvarint()
+		} else {
+			_, err = d.ReadSlice()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uint64MapCodec) skip(d *Decoder, t reflect.Type) error {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	if err = d.ensureElements(n, 9); err != nil {
+		return err
+	}
+	for range n {
+		if err = d.skipBytes(8); err != nil {
+			return err
+		}
+		if _, err = d.ReadUvarint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *reflectMapCodec) skip(d *Decoder, t reflect.Type) error {
+	n, err := d.skipLength(t)
+	if err != nil {
+		return err
+	}
+	if entryMin := wireMinBytes(c.key) + wireMinBytes(c.val); entryMin > 0 {
+		if err = d.ensureElements(n, entryMin); err != nil {
+			return err
+		}
+	}
+	for range n {
+		if err = c.skipKey(d, t.Key()); err != nil {
+			return err
+		}
+		if err = skipValue(d, c.val, t.Elem()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipKey mirrors readKey, which uses fixed widths for some primitive keys.
+func (c *reflectMapCodec) skipKey(d *Decoder, t reflect.Type) error {
+	if _, ok := c.key.(*primitiveCodec); !ok {
+		return skipValue(d, c.key, t)
+	}
+	switch t.Kind() {
+	case reflect.Int16, reflect.Uint16:
+		return d.skipBytes(2)
+	case reflect.Int32, reflect.Uint32:
+		return d.skipBytes(4)
+	case reflect.Int64, reflect.Uint64:
+		return d.skipBytes(8)
+	case reflect.String:
+		l, err := d.ReadUint16()
+		if err != nil {
+			return err
+		}
+		return d.skipBytes(uint64(l))
+	default:
+		return skipValue(d, c.key, t)
+	}
+}
+
+// ------------------------------------------------------------------------------
+
+func (c *reflectUnionCodec) skip(d *Decoder, _ reflect.Type) error {
+	tag, body, err := d.ReadTagged()
+	if err != nil {
+		return err
+	}
+	if arm := c.lookup(tag); arm != nil {
+		return skipBody(d, body, arm.codec, arm.elem)
+	}
+	return nil
+}
+
+func (c *reflectMixedCodec) skip(d *Decoder, t reflect.Type) error {
+	if err := c.fields.skip(d, t); err != nil {
+		return err
+	}
+	return c.union.skip(d, t)
+}
+
+func (c *oneOfCodec) skip(d *Decoder, _ reflect.Type) error {
+	tag, body, err := d.ReadTagged()
+	if err != nil || tag == 0 || tag > uint64(len(c.types)) {
+		return err
+	}
+	return skipBody(d, body, c.codecs[tag-1], c.types[tag-1])
+}
+
+func (c *versionedCodec) skip(d *Decoder, _ reflect.Type) error {
+	tag, body, err := d.ReadTagged()
+	switch {
+	case err != nil || tag == 0:
+		return err
+	case tag > uint64(len(c.types)):
+		return ErrUnknownVersion
+	}
+	return skipBody(d, body, c.codecs[tag-1], c.types[tag-1])
+}
+
+func (c *interfaceCodec) skip(d *Decoder, _ reflect.Type) error {
+	tag, body, err := d.ReadTagged()
+	if err != nil {
+		return err
+	}
+	i, ok := c.byTag[tag]
+	if !ok {
+		return nil
+	}
+	elem := c.arms[i].typ
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return skipBody(d, body, c.arms[i].codec, elem)
+}
+
+func (c *lazyCodec) skip(d *Decoder, _ reflect.Type) error {
+	_, err := d.ReadSlice()
+	return err
+}
@@ -0,0 +1,223 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type skipKey struct {
+	A int16
+	B string
+}
+
+type skipEverything struct {
+	String   string
+	Bool     bool
+	Int      int
+	Int8     int8
+	Uint     uint
+	Uint16   uint16
+	Float32  float32
+	Float64  float64
+	Complex  complex64
+	Complex2 complex128
+	Bytes    []byte
+	Bools    []bool
+	Strings  []string
+	Array    [2]string
+	Floats   []float64
+	Fixed    [3]float32
+	Varints  []int32
+	Varuints []uint64
+	Pointer  *textPayload
+	Nil      *textPayload
+	Pointers []*textPayload
+	Structs  []imagePayload
+	Matrix   [][2]int
+	Custom   time.Time
+	Optional *time.Time
+	Dict     map[string]string
+	Blobs    map[string][]byte
+	Counts   map[string]uint64
+	Numbers  map[uint64]uint64
+	Fixed16  map[int16]int32
+	Fixed32  map[uint32]string
+	Fixed64  map[int64]bool
+	Keyed    map[skipKey][]string
+	Union    payload
+	Mixed    mixedEnvelope
+	OneOf    OneOf2[textPayload, imagePayload]
+	Version  Versioned[docV3]
+	Shape    shape
+	Lazy     Lazy[docV2]
+	Raw      RawMessage[docV2]
+	Unknown  payloadProxy
+	Opaque   skipOpaque
+}
+
+func newSkipEverything() skipEverything {
+	now := time.Unix(1700000000, 0).UTC()
+	v := skipEverything{
+		String:   "hello",
+		Bool:     true,
+		Int:      -1000,
+		Int8:     -5,
+		Uint:     1 << 40,
+		Uint16:   300,
+		Float32:  1.5,
+		Float64:  -2.25,
+		Complex:  complex(1, 2),
+		Complex2: complex(3, 4),
+		Bytes:    []byte("bytes"),
+		Bools:    []bool{true, false, true},
+		Strings:  []string{"a", "", "ccc"},
+		Array:    [2]string{"x", "y"},
+		Floats:   []float64{1, 2, 3},
+		Fixed:    [3]float32{4, 5, 6},
+		Varints:  []int32{-1, 1 << 20},
+		Varuints: []uint64{0, 1 << 63},
+		Pointer:  &textPayload{Msg: "ptr"},
+		Pointers: []*textPayload{{Msg: "a"}, nil},
+		Structs:  []imagePayload{{Width: 1, Height: 2}},
+		Matrix:   [][2]int{{1, 2}, {3, 4}},
+		Custom:   now,
+		Optional: &now,
+		Dict:     map[string]string{"k": "v"},
+		Blobs:    map[string][]byte{"k": []byte("v")},
+		Counts:   map[string]uint64{"k": 7},
+		Numbers:  map[uint64]uint64{1: 2},
+		Fixed16:  map[int16]int32{-1: 2},
+		Fixed32:  map[uint32]string{3: "three"},
+		Fixed64:  map[int64]bool{4: true},
+		Keyed:    map[skipKey][]string{{A: 1, B: "b"}: {"v"}},
+		Union:    payload{Image: &imagePayload{Width: 3}},
+		Mixed:    mixedEnvelope{ID: 1, Trace: "t", Text: &textPayload{Msg: "m"}},
+		Version:  Versioned[docV3]{Value: &docV3{Title: "v3"}},
+		Shape:    &square{Side: 2},
+		Lazy:     NewLazy(&docV2{Title: "lazy"}),
+		Unknown:  payloadProxy{Unknown: UnknownArm{Tag: 9, Body: []byte{1, 'x'}}},
+		Opaque:   skipOpaque{Value: "opaque"},
+	}
+	v.OneOf.SetB(&imagePayload{Width: 5})
+	v.Raw, _ = Marshal(&docV2{Title: "raw"})
+	return v
+}
+
+func TestSkip(t *testing.T) {
+	in := newSkipEverything()
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	var out skipEverything
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in.Raw, out.Raw)
+
+	// The exact bytes of the value are captured, from memory and from a stream
+	typ := reflect.TypeFor[skipEverything]()
+	codec, err := scan(typ)
+	assert.NoError(t, err)
+
+	input := append(bytes.Clone(b), 0xff)
+	d := NewDecoder(bytes.NewBuffer(input))
+	raw, err := d.captureValue(codec, typ)
+	assert.NoError(t, err)
+	assert.Equal(t, b, raw)
+
+	d = NewDecoder(onlyReader{bytes.NewReader(input)})
+	raw, err = d.captureValue(codec, typ)
+	assert.NoError(t, err)
+	assert.Equal(t, b, raw)
+
+	// Any truncation is reported
+	for i := range b {
+		d := NewDecoder(bytes.NewBuffer(b[:i]))
+		assert.Error(t, skipValue(d, codec, typ), "truncated at %d", i)
+	}
+}
+
+type skipOpaque struct{ Value string }
+
+func (*skipOpaque) GetBinaryCodec() Codec { return skipOpaqueCodec{} }
+
+// skipOpaqueCodec cannot skip, so the value is decoded to be stepped over
+type skipOpaqueCodec struct{}
+
+func (skipOpaqueCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	e.WriteString(rv.Field(0).String())
+	return nil
+}
+
+func (skipOpaqueCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	v, err := d.ReadString()
+	rv.Field(0).SetString(v)
+	return err
+}
+
+type onlyReader struct {
+	r *bytes.Reader
+}
+
+func (r onlyReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func TestValidate(t *testing.T) {
+	in := newSkipEverything()
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b, &in))
+	assert.NoError(t, Validate(b, in))
+	for i := range b {
+		assert.Error(t, Validate(b[:i], &in), "truncated at %d", i)
+	}
+
+	// Union bodies are checked against their arm
+	assert.NoError(t, Validate([]byte{1, 1, 0}, payload{}))
+	assert.Error(t, Validate([]byte{1, 1, 5}, payload{}))
+	assert.NoError(t, Validate([]byte{9, 1, 5}, payload{}))
+
+	assert.Error(t, Validate(b, nil))
+	assert.Error(t, Validate(b, make(chan int)))
+	assert.Error(t, Validate([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, uint64(0)))
+}
+
+func TestValidateAllocs(t *testing.T) {
+	b, err := Marshal(&mixedEnvelope{ID: 1, Trace: "trace", Text: &textPayload{Msg: "hello"}})
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b, mixedEnvelope{}))
+
+	var v mixedEnvelope
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = Validate(b, &v)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestDecoderSkip(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	assert.NoError(t, e.Encode(&docV2{Title: "skipped", Body: "body"}))
+	assert.NoError(t, e.Encode(&textPayload{Msg: "kept"}))
+	encoded := bytes.Clone(buf.Bytes())
+
+	// Skipping consumes the value from buffers and streams alike
+	for _, d := range []*Decoder{
+		NewDecoder(&buf),
+		NewDecoder(onlyReader{bytes.NewReader(encoded)}),
+	} {
+		assert.NoError(t, d.Skip(reflect.TypeFor[docV2]()))
+		var out textPayload
+		assert.NoError(t, d.Decode(&out))
+		assert.Equal(t, "kept", out.Msg)
+		assert.Error(t, d.Skip(reflect.TypeFor[docV2]()))
+	}
+
+	assert.Error(t, NewDecoder(bytes.NewReader(encoded)).Skip(reflect.TypeFor[chan int]()))
+}
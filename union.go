@@ -4,23 +4,41 @@
 package binary
 
 import (
-	"bytes"
+	"cmp"
 	"errors"
 	"reflect"
-	"sync"
+	"slices"
 	"unsafe"
 )
 
 var ErrMultipleArms = errors.New("binary: multiple union arms set")
 
-type tagState struct {
-	bytes.Buffer
-	encoder Encoder
+// UnknownArm captures a union arm whose tag is not known to the decoding schema. Adding
+// a field of this type to a union struct lets it forward newer arms unchanged: they are
+// captured on decode and written back byte for byte when no known arm is set.
+type UnknownArm struct {
+	Tag  uint64
+	Body []byte
 }
 
-var tagBuffers = sync.Pool{New: func() any {
-	return new(tagState)
-}}
+// PeekTag reads the header of a union frame without decoding the arm, returning its
+// tag and the body that follows it.
+func PeekTag(b []byte) (tag uint64, body []byte, err error) {
+	r := sliceReader{buffer: b}
+	if tag, err = r.ReadUvarint(); err != nil {
+		return
+	}
+	var n uint64
+	if n, err = r.ReadUvarint(); err != nil {
+		return
+	}
+	l, err := decodeLength(n)
+	if err != nil {
+		return
+	}
+	body, err = r.Slice(l)
+	return
+}
 
 type unionArm struct {
 	tag    uint64
@@ -31,11 +49,22 @@ type unionArm struct {
 }
 
 type reflectUnionCodec struct {
-	arms  []unionArm
-	byTag []int // tag → index into arms; -1 = unknown; sized maxTag+1
+	arms    []unionArm
+	byTag   []int // tag → index into arms; -1 = unknown; sized maxTag+1, nil when sparse
+	sparse  []int // indices into arms, sorted by tag; used when tags exceed maxDenseTag
+	unknown int   // field index of the UnknownArm; -1 = none
 }
 
 func (c *reflectUnionCodec) lookup(tag uint64) *unionArm {
+	if c.sparse != nil {
+		i, ok := slices.BinarySearchFunc(c.sparse, tag, func(arm int, tag uint64) int {
+			return cmp.Compare(c.arms[arm].tag, tag)
+		})
+		if !ok {
+			return nil
+		}
+		return &c.arms[c.sparse[i]]
+	}
 	if tag >= uint64(len(c.byTag)) || c.byTag[tag] < 0 {
 		return nil
 	}
@@ -47,23 +76,16 @@ func (c *reflectUnionCodec) EncodeTo(e *Encoder, rv reflect.Value) (err error) {
 	case &errArm:
 		return ErrMultipleArms
 	case nil:
-		e.WriteTagged(0, nil)
+		if c.unknown >= 0 {
+			u := c.unknownArm(rv)
+			e.WriteTagged(u.Tag, u.Body)
+		} else {
+			e.WriteTagged(0, nil)
+		}
 		return e.err
 	}
-	state := tagBuffers.Get().(*tagState)
-	state.Buffer.Reset()
-	state.encoder.Reset(&state.Buffer)
-	err = selected.codec.EncodeTo(&state.encoder, elem)
-	if err == nil {
-		err = state.encoder.err
-	}
-	if err != nil {
-		tagBuffers.Put(state)
-		return err
-	}
-	e.WriteTagged(selected.tag, state.Bytes())
-	tagBuffers.Put(state)
-	return e.err
+	e.WriteUvarint(selected.tag)
+	return e.writeFramed(selected.codec, elem)
 }
 func (c *reflectUnionCodec) findArm(rv reflect.Value) (*unionArm, reflect.Value) {
 	if rv.CanAddr() {
@@ -122,8 +144,14 @@ func (c *reflectUnionCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 	arm := c.lookup(tag)
 	if arm == nil {
 		c.clearValue(rv)
+		if c.unknown >= 0 {
+			c.setUnknown(rv, tag, body)
+		}
 		return nil
 	}
+	if c.unknown >= 0 {
+		c.setUnknown(rv, 0, nil)
+	}
 	var ptr reflect.Value
 	if rv.CanAddr() {
 		base := unsafe.Pointer(rv.UnsafeAddr())
@@ -137,7 +165,7 @@ func (c *reflectUnionCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 	if !ptr.IsValid() {
 		ptr = reflect.New(arm.elem)
 	}
-	if err = c.decodeArm(d, arm.codec, body, ptr.Elem()); err != nil {
+	if err = decodeArm(d, arm.codec, body, ptr.Elem()); err != nil {
 		return err
 	}
 	if rv.CanAddr() {
@@ -148,6 +176,24 @@ func (c *reflectUnionCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
 	return nil
 }
 
+func (c *reflectUnionCodec) unknownArm(rv reflect.Value) UnknownArm {
+	f := rv.Field(c.unknown)
+	if f.CanAddr() {
+		return *(*UnknownArm)(unsafe.Pointer(f.UnsafeAddr()))
+	}
+	return f.Interface().(UnknownArm)
+}
+
+func (c *reflectUnionCodec) setUnknown(rv reflect.Value, tag uint64, body []byte) {
+	u := (*UnknownArm)(unsafe.Pointer(rv.Field(c.unknown).UnsafeAddr()))
+	if tag == 0 {
+		*u = UnknownArm{}
+		return
+	}
+	u.Tag = tag
+	u.Body = append(u.Body[:0], body...)
+}
+
 func (c *reflectUnionCodec) clearUnsafe(base unsafe.Pointer) {
 	for i := range c.arms {
 		*(*unsafe.Pointer)(unsafe.Add(base, c.arms[i].offset)) = nil
@@ -160,7 +206,8 @@ func (c *reflectUnionCodec) clearValue(rv reflect.Value) {
 	}
 }
 
-func (c *reflectUnionCodec) decodeArm(d *Decoder, codec Codec, body []byte, elem reflect.Value) error {
+// decodeArm decodes an arm body already read from the decoder into elem.
+func decodeArm(d *Decoder, codec Codec, body []byte, elem reflect.Value) error {
 	if d.slice != nil {
 		r := d.slice
 		buffer, offset := r.buffer, r.offset
@@ -173,13 +220,31 @@ func (c *reflectUnionCodec) decodeArm(d *Decoder, codec Codec, body []byte, elem
 		}
 		return err
 	}
-	dec := decoders.Get().(*Decoder)
-	dec.reader.(*sliceReader).Reset(body)
-	dec.last = nil
-	dec.codec = nil
-	dec.arena = nil
+	dec := d.borrow(body)
 	err := codec.DecodeTo(dec, elem)
-	dec.arena = nil
-	decoders.Put(dec)
+	release(dec)
 	return err
 }
+
+// ------------------------------------------------------------------------------
+
+// reflectMixedCodec encodes a struct holding both sequential fields and union arms:
+// the sequential fields come first, followed by the union frame.
+type reflectMixedCodec struct {
+	fields *reflectStructCodec
+	union  *reflectUnionCodec
+}
+
+func (c *reflectMixedCodec) EncodeTo(e *Encoder, rv reflect.Value) (err error) {
+	if err = c.fields.EncodeTo(e, rv); err != nil {
+		return
+	}
+	return c.union.EncodeTo(e, rv)
+}
+
+func (c *reflectMixedCodec) DecodeTo(d *Decoder, rv reflect.Value) (err error) {
+	if err = c.fields.DecodeTo(d, rv); err != nil {
+		return
+	}
+	return c.union.DecodeTo(d, rv)
+}
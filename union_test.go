@@ -9,6 +9,7 @@ import (
 	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -194,13 +195,6 @@ func TestUnionBranches(t *testing.T) {
 
 func TestUnionScan(t *testing.T) {
 	tests := map[string]interface{}{
-		"mixed fields": func() interface{} {
-			type mixed struct {
-				ID   uint64
-				Text *textPayload `binary:"1,union"`
-			}
-			return mixed{}
-		}(),
 		"non-pointer arm": func() interface{} {
 			type nonPtr struct {
 				Text textPayload `binary:"1,union"`
@@ -234,7 +228,7 @@ func TestUnionScan(t *testing.T) {
 		}(),
 		"tag out of range": func() interface{} {
 			type tooBig struct {
-				A *textPayload `binary:"256,union"`
+				A *textPayload `binary:"18446744073709551616,union"`
 			}
 			return tooBig{}
 		}(),
@@ -261,6 +255,271 @@ func TestUnionScan(t *testing.T) {
 	})
 }
 
+// ---- TestUnionMixed ----------------------------------------------------------
+
+type mixedEnvelope struct {
+	ID    uint64
+	Text  *textPayload `binary:"1,union"`
+	Trace string
+	Image *imagePayload `binary:"2,union"`
+}
+
+func TestUnionMixed(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		in := mixedEnvelope{ID: 42, Trace: "abc", Image: &imagePayload{Width: 3, Height: 4}}
+		b, err := Marshal(&in)
+		assert.NoError(t, err)
+
+		// Sequential fields come first, then the union frame
+		header, err := Marshal(struct {
+			ID    uint64
+			Trace string
+		}{42, "abc"})
+		assert.NoError(t, err)
+		assert.Equal(t, header, b[:len(header)])
+
+		var out mixedEnvelope
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+		assert.NoError(t, NewDecoder(bytes.NewReader(b)).Decode(&out))
+		assert.Equal(t, in, out)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		b, err := Marshal(mixedEnvelope{ID: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x1, 0x0, 0x0, 0x0}, b)
+
+		out := mixedEnvelope{Text: &textPayload{Msg: "old"}}
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, mixedEnvelope{ID: 1}, out)
+	})
+
+	t.Run("multiple arms", func(t *testing.T) {
+		_, err := Marshal(mixedEnvelope{Text: &textPayload{}, Image: &imagePayload{}})
+		assert.True(t, errors.Is(err, ErrMultipleArms))
+	})
+
+	t.Run("in a slice", func(t *testing.T) {
+		in := []mixedEnvelope{{ID: 1, Text: &textPayload{Msg: "a"}}, {ID: 2}}
+		b, err := Marshal(in)
+		assert.NoError(t, err)
+
+		var out []mixedEnvelope
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+		assert.Error(t, Unmarshal(b[:len(b)-1], &out))
+	})
+}
+
+// ---- TestUnionUnknown --------------------------------------------------------
+
+type payloadV2 struct {
+	Text  *textPayload  `binary:"1,union"`
+	Image *imagePayload `binary:"2,union"`
+	Doc   *docV2        `binary:"3,union"`
+}
+
+type payloadProxy struct {
+	Text    *textPayload `binary:"1,union"`
+	Unknown UnknownArm
+}
+
+type mixedProxy struct {
+	ID      uint64
+	Unknown UnknownArm
+	Text    *textPayload `binary:"1,union"`
+}
+
+func TestUnionUnknown(t *testing.T) {
+	t.Run("pass through", func(t *testing.T) {
+		in, err := Marshal(payloadV2{Doc: &docV2{Title: "a", Body: "b"}})
+		assert.NoError(t, err)
+
+		var proxy payloadProxy
+		assert.NoError(t, Unmarshal(in, &proxy))
+		assert.Nil(t, proxy.Text)
+		assert.Equal(t, uint64(3), proxy.Unknown.Tag)
+
+		out, err := Marshal(proxy)
+		assert.NoError(t, err)
+		assert.Equal(t, in, out)
+
+		var got payloadV2
+		assert.NoError(t, Unmarshal(out, &got))
+		assert.Equal(t, &docV2{Title: "a", Body: "b"}, got.Doc)
+	})
+
+	t.Run("owns body", func(t *testing.T) {
+		in, err := Marshal(payloadV2{Image: &imagePayload{Width: 1, Height: 2}})
+		assert.NoError(t, err)
+
+		var proxy payloadProxy
+		assert.NoError(t, Unmarshal(in, &proxy))
+		body := append([]byte(nil), proxy.Unknown.Body...)
+		clear(in)
+		assert.Equal(t, body, proxy.Unknown.Body)
+	})
+
+	t.Run("known arm clears unknown", func(t *testing.T) {
+		in, err := Marshal(payloadV2{Text: &textPayload{Msg: "hi"}})
+		assert.NoError(t, err)
+
+		proxy := payloadProxy{Unknown: UnknownArm{Tag: 9, Body: []byte{1}}}
+		assert.NoError(t, Unmarshal(in, &proxy))
+		assert.Equal(t, payloadProxy{Text: &textPayload{Msg: "hi"}}, proxy)
+
+		// A known arm takes precedence over the captured one
+		proxy.Unknown = UnknownArm{Tag: 9, Body: []byte{1}}
+		out, err := Marshal(&proxy)
+		assert.NoError(t, err)
+		assert.Equal(t, in, out)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		proxy := payloadProxy{Unknown: UnknownArm{Tag: 9, Body: []byte{1}}}
+		assert.NoError(t, Unmarshal([]byte{0, 0}, &proxy))
+		assert.Equal(t, payloadProxy{}, proxy)
+
+		out, err := Marshal(proxy)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0, 0}, out)
+	})
+
+	t.Run("mixed", func(t *testing.T) {
+		in, err := Marshal(struct {
+			ID   uint64
+			Body payloadV2
+		}{7, payloadV2{Doc: &docV2{Title: "x"}}})
+		assert.NoError(t, err)
+
+		var proxy mixedProxy
+		assert.NoError(t, Unmarshal(in, &proxy))
+		assert.Equal(t, uint64(7), proxy.ID)
+		assert.Equal(t, uint64(3), proxy.Unknown.Tag)
+
+		out, err := Marshal(&proxy)
+		assert.NoError(t, err)
+		assert.Equal(t, in, out)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		type dup struct {
+			A *textPayload `binary:"1,union"`
+			B UnknownArm
+			C UnknownArm
+		}
+		_, err := Marshal(dup{})
+		assert.Error(t, err)
+	})
+}
+
+// ---- TestUnionSparse ---------------------------------------------------------
+
+type sparsePayload struct {
+	Text  *textPayload  `binary:"2001,union"`
+	Image *imagePayload `binary:"1000,union"`
+	Doc   *docV2        `binary:"18446744073709551615,union"`
+	Small *docV1        `binary:"7,union"`
+}
+
+func TestUnionSparse(t *testing.T) {
+	codec, err := scan(reflect.TypeFor[sparsePayload]())
+	assert.NoError(t, err)
+	assert.Nil(t, codec.(*reflectUnionCodec).byTag)
+
+	for _, in := range []sparsePayload{
+		{Text: &textPayload{Msg: "hi"}},
+		{Image: &imagePayload{Width: 1, Height: 2}},
+		{Doc: &docV2{Title: "a", Body: "b"}},
+		{Small: &docV1{Title: "c"}},
+		{},
+	} {
+		b, err := Marshal(in)
+		assert.NoError(t, err)
+
+		var out sparsePayload
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+	}
+
+	b, err := Marshal(sparsePayload{Image: &imagePayload{}})
+	assert.NoError(t, err)
+	assert.Equal(t, stdbinary.AppendUvarint(nil, 1000), b[:2])
+
+	// Tags in between the registered ones are unknown
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.WriteTagged(1500, []byte{0x1, 'x'})
+	out := sparsePayload{Text: &textPayload{}}
+	assert.NoError(t, Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, sparsePayload{}, out)
+}
+
+type nestedPayload struct {
+	Outer *payload `binary:"1,union"`
+}
+
+func TestUnionLarge(t *testing.T) {
+	for _, size := range []int{0, 100, 200, 20000, 3 << 20} {
+		in := envelope{ID: 7, Body: payload{Text: &textPayload{Msg: strings.Repeat("x", size)}}}
+		b, err := Marshal(&in)
+		assert.NoError(t, err)
+
+		// The length written in place must match the one written from a scratch buffer
+		var out bytes.Buffer
+		assert.NoError(t, MarshalTo(&in, struct{ io.Writer }{&out}))
+		assert.Equal(t, b, out.Bytes())
+
+		var body []byte
+		body = stdbinary.AppendUvarint(body, uint64(size))
+		body = append(body, in.Body.Text.Msg...)
+		expect := stdbinary.AppendUvarint([]byte{7, 1}, uint64(len(body)))
+		assert.Equal(t, append(expect, body...), b)
+
+		var decoded envelope
+		assert.NoError(t, Unmarshal(b, &decoded))
+		assert.Equal(t, in, decoded)
+	}
+
+	// Nested unions are framed at every level, whichever way they are written
+	in := nestedPayload{Outer: &payload{Text: &textPayload{Msg: strings.Repeat("y", 300)}}}
+	b, err := Marshal(&in)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 0xb1, 0x02, 1, 0xae, 0x02, 0xac, 0x02}, b[:8])
+
+	var out bytes.Buffer
+	assert.NoError(t, MarshalTo(&in, struct{ io.Writer }{&out}))
+	assert.Equal(t, b, out.Bytes())
+
+	var decoded nestedPayload
+	assert.NoError(t, Unmarshal(b, &decoded))
+	assert.Equal(t, in, decoded)
+
+	// Errors from the arm surface from the scratch buffer
+	err = MarshalTo(&unionFailingEnvelope{Arm: &unionFailingPayload{}}, struct{ io.Writer }{&out})
+	assert.True(t, errors.Is(err, io.ErrClosedPipe))
+}
+
+func TestPeekTag(t *testing.T) {
+	b, err := Marshal(&payload{Image: &imagePayload{Width: 1, Height: 2}})
+	assert.NoError(t, err)
+
+	tag, body, err := PeekTag(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), tag)
+	assert.Equal(t, b[2:], body)
+
+	var image imagePayload
+	assert.NoError(t, Unmarshal(body, &image))
+	assert.Equal(t, imagePayload{Width: 1, Height: 2}, image)
+
+	for _, b := range [][]byte{nil, {1}, {1, 3, 0}, {0x80}} {
+		_, _, err := PeekTag(b)
+		assert.Error(t, err)
+	}
+}
+
 // ---- TestTagged --------------------------------------------------------------
 
 func TestTagged(t *testing.T) {
@@ -436,3 +695,34 @@ func TestUnionCodecDirect(t *testing.T) {
 		assert.True(t, buf.Len() > 0)
 	})
 }
+
+var countedEncodes int
+
+type countedValue struct{}
+
+func (countedValue) MarshalBinary() ([]byte, error) {
+	countedEncodes++
+	return []byte("x"), nil
+}
+func (*countedValue) UnmarshalBinary([]byte) error { return nil }
+
+type countedInner struct {
+	Value *countedValue `binary:"1,union"`
+}
+
+type countedOuter struct {
+	Inner *countedInner `binary:"1,union"`
+}
+
+func TestUnionEncodedOnce(t *testing.T) {
+	in := countedOuter{Inner: &countedInner{Value: &countedValue{}}}
+	expect, err := Marshal(&in)
+	assert.NoError(t, err)
+
+	// Nested arms are encoded once, even when the writer is not a buffer
+	countedEncodes = 0
+	var out bytes.Buffer
+	assert.NoError(t, NewEncoder(struct{ io.Writer }{&out}).Encode(&in))
+	assert.Equal(t, 1, countedEncodes)
+	assert.Equal(t, expect, out.Bytes())
+}
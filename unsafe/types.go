@@ -8,6 +8,7 @@ import (
 	"unsafe"
 
 	"github.com/kelindar/binary"
+	"github.com/kelindar/binary/internal/byteorder"
 )
 
 // ------------------------------------------------------------------------------
@@ -92,6 +93,30 @@ func (s *Float64s) GetBinaryCodec() binary.Codec { return integerCodec[Float64s]
 
 // ------------------------------------------------------------------------------
 
+type Float16s []Float16
+
+func (s *Float16s) GetBinaryCodec() binary.Codec { return integerCodec[Float16s](2) }
+
+// ------------------------------------------------------------------------------
+
+type BFloat16s []BFloat16
+
+func (s *BFloat16s) GetBinaryCodec() binary.Codec { return integerCodec[BFloat16s](2) }
+
+// ------------------------------------------------------------------------------
+
+type QInt8s struct {
+	Scale  float32
+	Offset float32
+	Values []int8
+}
+
+func (s *QInt8s) GetBinaryCodec() binary.Codec {
+	return &quantizedCodec{values: integerSliceCodec{sliceType: reflect.TypeFor[[]int8](), sizeOfInt: 1}}
+}
+
+// ------------------------------------------------------------------------------
+
 type integerSliceCodec struct {
 	sliceType reflect.Type
 	sizeOfInt int
@@ -109,7 +134,7 @@ func integerCodec[T any](size int) binary.Codec {
 }
 func (c *integerSliceCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) (err error) {
 	n := rv.Len() * c.sizeOfInt
-	e.WriteUint64(uint64(rv.Len()))
+	e.WriteUint64(uint64(rv.Len()) | byteorder.Native)
 	e.Write(unsafe.Slice((*byte)(rv.UnsafePointer()), n))
 	return
 }
@@ -118,7 +143,8 @@ func (c *integerSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err e
 	if l, err = d.ReadUint64(); err != nil {
 		return
 	}
-	if l == 0 {
+	order := l & byteorder.Flag
+	if l &^= byteorder.Flag; l == 0 {
 		rv.SetZero()
 		return nil
 	}
@@ -131,9 +157,37 @@ func (c *integerSliceCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err e
 	}
 	size := n * c.sizeOfInt
 	src := reflect.MakeSlice(c.sliceType, n, n)
-	if _, err = io.ReadFull(d, unsafe.Slice((*byte)(src.UnsafePointer()), size)); err != nil {
+	data := unsafe.Slice((*byte)(src.UnsafePointer()), size)
+	if _, err = io.ReadFull(d, data); err != nil {
 		return err
 	}
+	if order != byteorder.Native {
+		byteorder.Swap(data, c.sizeOfInt)
+	}
 	rv.Set(src)
 	return
 }
+
+// ------------------------------------------------------------------------------
+
+type quantizedCodec struct {
+	values integerSliceCodec
+}
+
+func (c *quantizedCodec) EncodeTo(e *binary.Encoder, rv reflect.Value) error {
+	e.WriteFloat32(float32(rv.Field(0).Float()))
+	e.WriteFloat32(float32(rv.Field(1).Float()))
+	return c.values.EncodeTo(e, rv.Field(2))
+}
+func (c *quantizedCodec) DecodeTo(d *binary.Decoder, rv reflect.Value) (err error) {
+	var scale, offset float32
+	if scale, err = d.ReadFloat32(); err != nil {
+		return
+	}
+	if offset, err = d.ReadFloat32(); err != nil {
+		return
+	}
+	rv.Field(0).SetFloat(float64(scale))
+	rv.Field(1).SetFloat(float64(offset))
+	return c.values.DecodeTo(d, rv.Field(2))
+}
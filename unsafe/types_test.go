@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/kelindar/binary"
+	"github.com/kelindar/binary/internal/byteorder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -101,6 +102,28 @@ func TestIntegerDecodeErrors(t *testing.T) {
 	assert.NoError(t, binary.Unmarshal(data, &values))
 }
 
+func TestByteOrder(t *testing.T) {
+	encoded, err := binary.Marshal(Uint32s{1, 2, 0x01020304})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), stdbinary.LittleEndian.Uint64(encoded[:8]))
+
+	// Simulate a payload written by a big-endian host
+	foreign := stdbinary.LittleEndian.AppendUint64(nil, 3|byteorder.Flag)
+	for _, v := range []uint32{1, 2, 0x01020304} {
+		foreign = stdbinary.BigEndian.AppendUint32(foreign, v)
+	}
+
+	var out Uint32s
+	assert.NoError(t, binary.Unmarshal(foreign, &out))
+	assert.Equal(t, Uint32s{1, 2, 0x01020304}, out)
+
+	floats := stdbinary.LittleEndian.AppendUint64(nil, 1|byteorder.Flag)
+	floats = stdbinary.BigEndian.AppendUint64(floats, 0x400c000000000000)
+	var f Float64s
+	assert.NoError(t, binary.NewDecoder(bytes.NewReader(floats)).Decode(&f))
+	assert.Equal(t, Float64s{3.5}, f)
+}
+
 func TestSort(t *testing.T) {
 	tests := map[string]struct {
 		value sort.Interface
@@ -194,7 +217,7 @@ func FuzzDecodeWire(f *testing.F) {
 // ponytail: cap fuzz element counts at 4096; keep huge-length cases in
 // directed tests unless subprocess isolation is added.
 func unsafeFuzzCountTooLarge(wire []byte) bool {
-	return len(wire) >= 8 && stdbinary.LittleEndian.Uint64(wire[:8]) > 4096
+	return len(wire) >= 8 && stdbinary.LittleEndian.Uint64(wire[:8])&^byteorder.Flag > 4096
 }
 
 func fuzzOutput(kind byte) any {
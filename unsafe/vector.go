@@ -0,0 +1,168 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package unsafe
+
+import (
+	"math"
+)
+
+// Float16 is an IEEE 754 half-precision floating-point number.
+type Float16 uint16
+
+// ToFloat16 converts a float32 to half precision, rounding to the nearest even value.
+func ToFloat16(f float32) Float16 {
+	b := math.Float32bits(f)
+	sign := uint32(b>>16) & 0x8000
+	exp := int32(b>>23) & 0xff
+	mant := b & 0x7fffff
+	if exp == 0xff {
+		if mant != 0 {
+			return Float16(sign | 0x7e00) // NaN
+		}
+		return Float16(sign | 0x7c00) // Inf
+	}
+
+	switch e := exp - 127 + 15; {
+	case e >= 0x1f:
+		return Float16(sign | 0x7c00)
+	case e <= 0:
+		if e < -10 {
+			return Float16(sign)
+		}
+
+		// Subnormal, shift the mantissa along with its implicit bit
+		mant |= 0x800000
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem, mid := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return Float16(sign | half)
+	default:
+		half := uint32(e)<<10 | mant>>13
+		if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+			half++ // a carry rounds into the exponent, up to Inf
+		}
+		return Float16(sign | half)
+	}
+}
+
+// Float32 converts the value to a float32, which represents it exactly.
+func (h Float16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
+
+// NewFloat16s converts a slice of float32 to half precision.
+func NewFloat16s(v []float32) Float16s {
+	out := make(Float16s, len(v))
+	for i, f := range v {
+		out[i] = ToFloat16(f)
+	}
+	return out
+}
+
+// Float32s converts the slice back to single precision.
+func (s Float16s) Float32s() Float32s {
+	out := make(Float32s, len(s))
+	for i, h := range s {
+		out[i] = h.Float32()
+	}
+	return out
+}
+
+// ------------------------------------------------------------------------------
+
+// BFloat16 is a brain floating-point number, which keeps the exponent range of a
+// float32 but only 8 bits of precision.
+type BFloat16 uint16
+
+// ToBFloat16 converts a float32 to a bfloat16, rounding to the nearest even value.
+func ToBFloat16(f float32) BFloat16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 {
+		return BFloat16(b>>16 | 0x40) // keep NaN quiet after truncation
+	}
+	b += 0x7fff + (b>>16)&1
+	return BFloat16(b >> 16)
+}
+
+// Float32 converts the value to a float32, which represents it exactly.
+func (h BFloat16) Float32() float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// NewBFloat16s converts a slice of float32 to bfloat16.
+func NewBFloat16s(v []float32) BFloat16s {
+	out := make(BFloat16s, len(v))
+	for i, f := range v {
+		out[i] = ToBFloat16(f)
+	}
+	return out
+}
+
+// Float32s converts the slice back to single precision.
+func (s BFloat16s) Float32s() Float32s {
+	out := make(Float32s, len(s))
+	for i, h := range s {
+		out[i] = h.Float32()
+	}
+	return out
+}
+
+// ------------------------------------------------------------------------------
+
+// Quantize maps a slice of float32 linearly onto int8 values, so that each value is
+// approximately Offset + Values[i]*Scale.
+func Quantize(v []float32) QInt8s {
+	if len(v) == 0 {
+		return QInt8s{}
+	}
+
+	lo, hi := v[0], v[0]
+	for _, f := range v[1:] {
+		lo, hi = min(lo, f), max(hi, f)
+	}
+
+	q := QInt8s{
+		Scale:  (hi - lo) / 254,
+		Offset: lo + (hi-lo)/2,
+		Values: make([]int8, len(v)),
+	}
+	if q.Scale == 0 {
+		return q
+	}
+
+	for i, f := range v {
+		r := math.Round(float64((f - q.Offset) / q.Scale))
+		q.Values[i] = int8(max(-127, min(127, r)))
+	}
+	return q
+}
+
+// Float32s converts the quantized values back to single precision.
+func (q QInt8s) Float32s() Float32s {
+	out := make(Float32s, len(q.Values))
+	for i, v := range q.Values {
+		out[i] = q.Offset + float32(v)*q.Scale
+	}
+	return out
+}
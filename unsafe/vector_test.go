@@ -0,0 +1,105 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package unsafe
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kelindar/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat16(t *testing.T) {
+	tests := map[float32]Float16{
+		0:                     0x0000,
+		1:                     0x3c00,
+		-2:                    0xc000,
+		0.5:                   0x3800,
+		65504:                 0x7bff,
+		65520:                 0x7c00, // rounds up to Inf
+		1e-7:                  0x0002, // subnormal
+		5.960464477539063e-8:  0x0001,
+		1e-9:                  0x0000,
+		float32(math.Inf(1)):  0x7c00,
+		float32(math.Inf(-1)): 0xfc00,
+		1.0009765625:          0x3c01,
+		1.00048828125:         0x3c00, // ties to even
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, ToFloat16(in), "%v", in)
+	}
+
+	for _, v := range []float32{0, 1, -2, 0.5, 65504, 5.960464477539063e-8, 6.097555160522461e-5, 0.333251953125} {
+		assert.Equal(t, v, ToFloat16(v).Float32())
+	}
+	assert.True(t, math.IsNaN(float64(ToFloat16(float32(math.NaN())).Float32())))
+	assert.True(t, math.IsInf(float64(Float16(0x7c00).Float32()), 1))
+}
+
+func TestBFloat16(t *testing.T) {
+	tests := map[float32]BFloat16{
+		0:                    0x0000,
+		1:                    0x3f80,
+		-2:                   0xc000,
+		3.140625:             0x4049,
+		float32(math.Inf(1)): 0x7f80,
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, ToBFloat16(in), "%v", in)
+		assert.Equal(t, in, ToBFloat16(in).Float32())
+	}
+	assert.True(t, math.IsNaN(float64(ToBFloat16(float32(math.NaN())).Float32())))
+	assert.Equal(t, float32(1.0078125), ToBFloat16(1.005).Float32())
+}
+
+func TestVectors(t *testing.T) {
+	input := []float32{-1.5, 0, 0.25, 3, 1024}
+
+	t.Run("float16", func(t *testing.T) {
+		in := NewFloat16s(input)
+		b, err := binary.Marshal(&in)
+		assert.NoError(t, err)
+		assert.Equal(t, 8+2*len(input), len(b))
+
+		var out Float16s
+		assert.NoError(t, binary.Unmarshal(b, &out))
+		assert.Equal(t, Float32s(input), out.Float32s())
+	})
+
+	t.Run("bfloat16", func(t *testing.T) {
+		in := NewBFloat16s(input)
+		b, err := binary.Marshal(&in)
+		assert.NoError(t, err)
+		assert.Equal(t, 8+2*len(input), len(b))
+
+		var out BFloat16s
+		assert.NoError(t, binary.Unmarshal(b, &out))
+		assert.Equal(t, Float32s(input), out.Float32s())
+	})
+
+	t.Run("quantized", func(t *testing.T) {
+		in := Quantize(input)
+		b, err := binary.Marshal(in)
+		assert.NoError(t, err)
+		assert.Equal(t, 8+8+len(input), len(b))
+
+		var out QInt8s
+		assert.NoError(t, binary.Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+		for i, v := range out.Float32s() {
+			assert.InDelta(t, input[i], v, float64(in.Scale)/2+1e-3)
+		}
+	})
+
+	t.Run("quantized constant", func(t *testing.T) {
+		in := Quantize([]float32{2, 2, 2})
+		assert.Equal(t, Float32s{2, 2, 2}, in.Float32s())
+		assert.Equal(t, QInt8s{}, Quantize(nil))
+
+		var out QInt8s
+		assert.Error(t, binary.Unmarshal([]byte{1, 2, 3}, &out))
+		assert.Error(t, binary.Unmarshal(make([]byte, 6), &out))
+	})
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrUnknownVersion is returned when decoding a Versioned value whose version is not part
+// of the migration chain, typically because it was written by a newer schema.
+var ErrUnknownVersion = errors.New("binary: unknown version")
+
+var migrations = struct {
+	sync.Mutex
+	byTarget map[reflect.Type]migration
+	scanned  map[reflect.Type]bool // versions of the chains already scanned
+}{byTarget: make(map[reflect.Type]migration), scanned: make(map[reflect.Type]bool)}
+
+type migration struct {
+	from reflect.Type
+	fn   func(any) (any, error)
+}
+
+// Migrate registers the function upgrading a value of a version to the next one. Each
+// version has a single predecessor, and a Versioned value is encoded as a union with
+// one arm per version of its chain, tagged from 1 for the oldest. Migrations must be
+// registered before the Versioned type is first encoded or decoded, typically in init,
+// since its chain is then cached. It panics if a migration to the same version already
+// exists, or if that version is part of a chain that was already used.
+func Migrate[From, To any](fn func(*From) (*To, error)) {
+	from, to := reflect.TypeFor[From](), reflect.TypeFor[To]()
+	if from == to {
+		panic("binary: cannot migrate " + from.String() + " to itself")
+	}
+
+	migrations.Lock()
+	defer migrations.Unlock()
+	if _, ok := migrations.byTarget[to]; ok {
+		panic("binary: duplicate migration to " + to.String())
+	}
+	if migrations.scanned[to] {
+		panic("binary: migration to " + to.String() + " registered after its Versioned type was used")
+	}
+	migrations.byTarget[to] = migration{from: from, fn: func(v any) (any, error) {
+		out, err := fn(v.(*From))
+		switch {
+		case err != nil:
+			return nil, err
+		case out == nil:
+			return nil, errors.New("binary: migration to " + to.String() + " returned nil")
+		}
+		return out, nil
+	}}
+}
+
+// Versioned holds the latest version of a value. Decoding accepts any version of its
+// migration chain and upgrades it by running the registered migrations, while encoding
+// always writes the latest version. A nil value is encoded as tag 0.
+type Versioned[T any] struct {
+	Value *T
+}
+
+func (*Versioned[T]) scanCodec() (Codec, error) {
+	return scanVersioned(reflect.TypeFor[T]())
+}
+
+func scanVersioned(t reflect.Type) (Codec, error) {
+	migrations.Lock()
+	types := []reflect.Type{t}
+	var steps []func(any) (any, error)
+	for {
+		m, ok := migrations.byTarget[types[0]]
+		if !ok {
+			break
+		}
+		for _, seen := range types {
+			if seen == m.from {
+				migrations.Unlock()
+				return nil, errors.New("binary: migration cycle through " + m.from.String())
+			}
+		}
+		types = append([]reflect.Type{m.from}, types...)
+		steps = append([]func(any) (any, error){m.fn}, steps...)
+	}
+	for _, typ := range types {
+		migrations.scanned[typ] = true
+	}
+	migrations.Unlock()
+
+	c := &versionedCodec{types: types, steps: steps, codecs: make([]Codec, len(types))}
+	for i, typ := range types {
+		codec, err := scanType(typ)
+		if err != nil {
+			return nil, err
+		}
+		c.codecs[i] = codec
+	}
+	return c, nil
+}
+
+// versionedCodec encodes a Versioned value as a union of the versions of its chain.
+type versionedCodec struct {
+	types  []reflect.Type           // oldest first, the last one is the latest version
+	codecs []Codec                  // codec of each version
+	steps  []func(any) (any, error) // steps[i] upgrades types[i] to types[i+1]
+}
+
+func (c *versionedCodec) EncodeTo(e *Encoder, rv reflect.Value) error {
+	value := rv.Field(0)
+	if value.IsNil() {
+		e.WriteTagged(0, nil)
+		return e.err
+	}
+	e.WriteUvarint(uint64(len(c.types)))
+	return e.writeFramed(c.codecs[len(c.codecs)-1], value.Elem())
+}
+
+func (c *versionedCodec) DecodeTo(d *Decoder, rv reflect.Value) error {
+	tag, body, err := d.ReadTagged()
+	switch {
+	case err != nil:
+		return err
+	case tag > uint64(len(c.types)):
+		return ErrUnknownVersion
+	}
+	value := rv.Field(0)
+	if tag == 0 {
+		value.SetZero()
+		return nil
+	}
+
+	// The latest version is decoded in place, older ones are upgraded step by step
+	latest := tag == uint64(len(c.types))
+	ptr := value
+	if !latest || ptr.IsNil() {
+		ptr = reflect.New(c.types[tag-1])
+	}
+	if err := decodeArm(d, c.codecs[tag-1], body, ptr.Elem()); err != nil {
+		return err
+	}
+	if latest {
+		value.Set(ptr)
+		return nil
+	}
+	out := ptr.Interface()
+	for _, step := range c.steps[tag-1:] {
+		if out, err = step(out); err != nil {
+			return err
+		}
+	}
+	value.Set(reflect.ValueOf(out))
+	return nil
+}
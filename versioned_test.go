@@ -0,0 +1,129 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type docV3 struct {
+	Title string
+	Body  string
+	Pages int
+}
+
+type cycleA struct{ A int }
+type cycleB struct{ B int }
+
+func init() {
+	Migrate(func(v *docV1) (*docV2, error) {
+		return &docV2{Title: v.Title}, nil
+	})
+	Migrate(func(v *docV2) (*docV3, error) {
+		if v.Body == "fail" {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &docV3{Title: v.Title, Body: v.Body, Pages: 1}, nil
+	})
+	Migrate(func(v *cycleA) (*cycleB, error) { return nil, nil })
+	Migrate(func(v *cycleB) (*cycleA, error) { return nil, nil })
+}
+
+func TestVersioned(t *testing.T) {
+	in := Versioned[docV3]{Value: &docV3{Title: "a", Body: "b", Pages: 3}}
+	b, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(3), b[0])
+
+	var out Versioned[docV3]
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+
+	// Older versions are upgraded through the chain
+	for _, old := range []any{
+		&doc{V1: &docV1{Title: "a"}},
+		&doc{V2: &docV2{Title: "a"}},
+	} {
+		b, err := Marshal(old)
+		assert.NoError(t, err)
+		assert.NoError(t, Unmarshal(b, &out))
+		assert.Equal(t, &docV3{Title: "a", Pages: 1}, out.Value)
+	}
+
+	// A reader in the middle of the chain sees the same tags as the union
+	b, err = Marshal(Versioned[docV2]{Value: &docV2{Title: "x", Body: "y"}})
+	assert.NoError(t, err)
+	var union doc
+	assert.NoError(t, Unmarshal(b, &union))
+	assert.Equal(t, &docV2{Title: "x", Body: "y"}, union.V2)
+
+	// Nil values are written as an empty union
+	b, err = Marshal(&Versioned[docV3]{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0}, b)
+	assert.NoError(t, Unmarshal(b, &out))
+	assert.Nil(t, out.Value)
+}
+
+func TestVersionedErrors(t *testing.T) {
+	var out Versioned[docV2]
+	b, err := Marshal(Versioned[docV3]{Value: &docV3{}})
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(Unmarshal(b, &out), ErrUnknownVersion))
+	assert.True(t, errors.Is(Validate(b, &out), ErrUnknownVersion))
+	assert.True(t, errors.Is(Validate([]byte{9, 1, 0}, &out), ErrUnknownVersion))
+
+	b, err = Marshal(&doc{V2: &docV2{Body: "fail"}})
+	assert.NoError(t, err)
+	var latest Versioned[docV3]
+	assert.Equal(t, io.ErrUnexpectedEOF, Unmarshal(b, &latest))
+
+	var a Versioned[cycleA]
+	_, err = Marshal(&a)
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		Migrate(func(v *docV1) (*docV2, error) { return nil, nil })
+	})
+	assert.Panics(t, func() {
+		Migrate(func(v *docV1) (*docV1, error) { return nil, nil })
+	})
+}
+
+func TestMigrateAfterUse(t *testing.T) {
+	type lateV1 struct{ A int }
+	type lateV2 struct{ A, B int }
+	type lateV0 struct{}
+
+	_, err := Marshal(Versioned[lateV2]{Value: &lateV2{}})
+	assert.NoError(t, err)
+
+	// The chain is cached on first use, so it can no longer be extended
+	assert.Panics(t, func() {
+		Migrate(func(v *lateV1) (*lateV2, error) { return nil, nil })
+	})
+	assert.NotPanics(t, func() {
+		Migrate(func(v *lateV0) (*lateV1, error) { return nil, nil })
+	})
+}
+
+func TestVersionedNil(t *testing.T) {
+	type oldV struct{ A int }
+	type newV struct{ A int }
+	Migrate(func(v *oldV) (*newV, error) { return nil, nil })
+
+	body, err := Marshal(&oldV{A: 1})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	NewEncoder(&buf).WriteTagged(1, body)
+
+	// A migration returning nil is reported rather than leaving a nil value behind
+	out := Versioned[newV]{Value: &newV{A: 2}}
+	assert.Error(t, Unmarshal(buf.Bytes(), &out))
+}